@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/changelog"
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+	"github.com/gudlyf/GudCommit/golang/pkg/convention"
+	"github.com/gudlyf/GudCommit/golang/pkg/redact"
+)
+
+// typeSections maps conventional commit types to the changelog section their
+// commits go under. Types listed with "" are left out of the changelog, and
+// any other type is a change.
+var typeSections = map[string]string{
+	"feat":     "Added",
+	"fix":      "Fixed",
+	"security": "Security",
+	"perf":     "Changed",
+	"refactor": "Changed",
+	"revert":   "Changed",
+	"build":    "",
+	"chore":    "",
+	"ci":       "",
+	"docs":     "",
+	"style":    "",
+	"test":     "",
+}
+
+var (
+	// removalPattern matches descriptions of removed functionality
+	removalPattern = regexp.MustCompile(`(?i)^(remove|drop|delete)\b`)
+	// deprecationPattern matches descriptions of deprecated functionality
+	deprecationPattern = regexp.MustCompile(`(?i)^deprecate`)
+)
+
+// historyCommit is a commit in the range and its parsed message
+type historyCommit struct {
+	sha     string
+	message string
+	parsed  convention.Message
+	// conventional is false when the message does not follow the grammar
+	conventional bool
+}
+
+// rangeCommits returns the commits in r, oldest first, leaving out merges
+func rangeCommits(ctx context.Context, r changelogRange) ([]historyCommit, error) {
+	out, err := gitText(ctx, "log", "--reverse", "--no-merges", "--format=%H%x00%B%x1e", r.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git log: %w", err)
+	}
+	var commits []historyCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		sha, message, ok := strings.Cut(strings.TrimLeft(record, "\n"), "\x00")
+		if !ok {
+			continue
+		}
+		c := historyCommit{sha: sha, message: strings.TrimSpace(message)}
+		c.parsed, c.conventional = convention.ParseMessage(message)
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// groupCommits sorts conventional commits into changelog sections, keeping
+// their order and dropping repeated entries. It returns the commits that are
+// not conventional separately.
+func groupCommits(opts changelog.Options, commits []historyCommit) (changelog.Entry, []historyCommit) {
+	entry := changelog.Entry{}
+	seen := map[string]bool{}
+	var other []historyCommit
+	for _, c := range commits {
+		if !c.conventional {
+			other = append(other, c)
+			continue
+		}
+		m := c.parsed
+		section := commitSection(opts, m)
+		if section == "" {
+			// Breaking changes are listed whatever their type
+			if !m.IsBreaking() {
+				continue
+			}
+			section = "Changed"
+		}
+
+		text := m.Description
+		if m.Scope != "" {
+			text = fmt.Sprintf("**%s:** %s", m.Scope, text)
+		}
+		if m.IsBreaking() {
+			text = "**BREAKING:** " + text
+		}
+		if seen[section+text] {
+			continue
+		}
+		seen[section+text] = true
+		entry.Add(section, text)
+	}
+	return entry, other
+}
+
+// commitSection returns the section a conventional commit goes under, or ""
+// when its type is left out of the changelog. A custom section configured
+// for the type comes first, then the type. Commits in the security scope go
+// under Security, and changes whose description says something was removed
+// or deprecated under Removed or Deprecated.
+func commitSection(opts changelog.Options, m convention.Message) string {
+	if name, ok := opts.SectionForType(m.Type); ok {
+		return name
+	}
+	section, known := typeSections[m.Type]
+	switch {
+	case known && section == "":
+		return ""
+	case strings.EqualFold(m.Scope, "security"):
+		return "Security"
+	case known && section != "Changed":
+		return section
+	case removalPattern.MatchString(m.Description):
+		return "Removed"
+	case deprecationPattern.MatchString(m.Description):
+		return "Deprecated"
+	}
+	return "Changed"
+}
+
+// historySection builds the changelog section for r from the commit log.
+// With polish the model rewords the entries and summarises the commits that
+// are not conventional; otherwise no model is used and those are left out.
+func historySection(ctx context.Context, cfg *config.Config, r changelogRange, polish bool) (string, error) {
+	commits, err := rangeCommits(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		fmt.Fprintln(status, ">> No commits found in", r)
+		return "", nil
+	}
+
+	entry, other := groupCommits(cfg.Changelog, commits)
+	if polish {
+		polished, err := polishEntry(ctx, cfg, entry, other)
+		var secrets *redact.FoundError
+		switch {
+		case err == nil:
+			entry = polished
+		case ctx.Err() != nil:
+			return "", ctx.Err()
+		case errors.As(err, &secrets):
+			return "", err
+		default:
+			fmt.Fprintf(status, "⚠ :: Keeping the entries as written in the commits: %v\n", err)
+		}
+	} else if len(other) > 0 {
+		subjects := make([]string, len(other))
+		for i, c := range other {
+			subjects[i], _, _ = strings.Cut(c.message, "\n")
+		}
+		fmt.Fprintf(status, "⚠ :: Left out %d commit(s) that do not follow Conventional Commits (use --polish to summarise them): %s\n",
+			len(other), strings.Join(subjects, "; "))
+	}
+
+	if entry.Empty() {
+		fmt.Fprintln(status, ">> No changelog entries in", r)
+		return "", nil
+	}
+	return formatChangelog(cfg.Changelog, entry, r.heading), nil
+}
+
+// polishEntry asks the model to reword the entries and to summarise the
+// commits that are not conventional into the sections
+func polishEntry(ctx context.Context, cfg *config.Config, entry changelog.Entry, other []historyCommit) (changelog.Entry, error) {
+	entries, err := cfg.Changelog.MarshalEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, c := range other {
+		messages = append(messages, "- "+strings.ReplaceAll(c.message, "\n", "\n  "))
+	}
+	commitLog, err := redactText(cfg, strings.Join(messages, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	if commitLog == "" {
+		commitLog = "(none)"
+	}
+
+	prompt := fmt.Sprintf(`These changelog entries were built from conventional commit messages:
+
+%s
+
+These commits do not follow Conventional Commits:
+
+%s
+
+Polish the wording of the entries and add entries summarising the other commits, then respond with JSON matching this exact schema:
+
+%s
+
+Rules:
+- Follow Keep a Changelog format (http://keepachangelog.com/)
+- Keep every entry in its section and in the same order; only improve the wording
+- Keep markers such as **BREAKING:** and **scope:** prefixes
+- Leave out other commits that only touch tests, documentation, formatting or the build
+%s
+- Do not include any explanatory text outside the JSON`, entries, commitLog, cfg.Changelog.Schema(), cfg.Changelog.PromptRules())
+
+	completion, err := generate(ctx, cfg, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Changelog.Parse(completion)
+}
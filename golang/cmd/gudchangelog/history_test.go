@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/changelog"
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+	"github.com/gudlyf/GudCommit/golang/pkg/convention"
+)
+
+func TestGroupCommits(t *testing.T) {
+	var commits []historyCommit
+	for _, message := range []string{
+		"feat(api): add retries",
+		"fix: handle empty bodies",
+		"docs: describe retries",
+		"Update things",
+		"perf(db): batch inserts",
+		"feat(api): add retries",
+		"refactor: remove the v1 client",
+		"chore!: require Go 1.21",
+		"deploy: roll out to staging",
+		"fix(api): retry on 503\n\nBREAKING CHANGE: 503s are no longer returned",
+		"refactor: deprecate the v1 routes",
+		"fix(security): escape HTML in errors",
+		"build: bump the TLS library",
+		"test: remove flaky retry test",
+		"chore: delete tmp script",
+		"docs: drop old FAQ",
+		"ci(security): pin action versions",
+		"fix: remove crash on empty body",
+		"revert: drop the cache",
+	} {
+		c := historyCommit{message: message}
+		c.parsed, c.conventional = convention.ParseMessage(message)
+		commits = append(commits, c)
+	}
+
+	entry, other := groupCommits(changelog.DefaultOptions(), commits)
+	expected := changelog.Entry{
+		"Added":      {"**api:** add retries"},
+		"Changed":    {"**db:** batch inserts", "**BREAKING:** require Go 1.21", "roll out to staging"},
+		"Deprecated": {"deprecate the v1 routes"},
+		"Removed":    {"remove the v1 client", "drop the cache"},
+		"Fixed":      {"handle empty bodies", "**BREAKING:** **api:** retry on 503", "remove crash on empty body"},
+		"Security":   {"**security:** escape HTML in errors"},
+	}
+	if !reflect.DeepEqual(entry, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, entry)
+	}
+	if len(other) != 1 || other[0].message != "Update things" {
+		t.Errorf("Expected the non-conventional commit apart, got %+v", other)
+	}
+
+	// Custom sections collect the types configured for them
+	opts := changelog.Options{Sections: []changelog.Section{
+		{Name: "Performance", Types: []string{"perf"}},
+		{Name: "Dependencies", Types: []string{"build"}},
+	}}
+	entry, _ = groupCommits(opts, commits)
+	if !reflect.DeepEqual(entry["Performance"], []string{"**db:** batch inserts"}) || !reflect.DeepEqual(entry["Dependencies"], []string{"bump the TLS library"}) {
+		t.Errorf("Expected the custom sections filled, got %+v", entry)
+	}
+	if got := formatChangelog(opts, entry, unreleased); !strings.HasSuffix(got, "### Security\n- **security:** escape HTML in errors\n\n### Performance\n- **db:** batch inserts\n\n### Dependencies\n- bump the TLS library\n\n") {
+		t.Errorf("Expected the custom sections after the standard ones, got:\n%s", got)
+	}
+}
+
+func TestHistorySection(t *testing.T) {
+	dir := taggedRepo(t)
+	quiet(t)
+	commitFile(t, dir, "d.txt", "d\n", "WIP")
+	ctx := context.Background()
+	r := changelogRange{from: "v1.0.0", to: "HEAD", heading: unreleased}
+
+	// Without --polish no model is configured or needed
+	cfg := config.Defaults()
+	section, err := historySection(ctx, cfg, r, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "## [Unreleased]\n\n### Added\n- add b\n\n### Fixed\n- add c\n\n"; section != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, section)
+	}
+
+	prompts := fakeModel(t, dir, `{"changelog": {"added": ["Add b"], "changed": ["Work in progress"], "removed": [], "fixed": ["Add c"]}}`)
+	if cfg, err = config.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if section, err = historySection(ctx, cfg, r, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(section, "### Changed\n- Work in progress\n") {
+		t.Errorf("Expected the polished entries, got:\n%s", section)
+	}
+	if len(*prompts) != 1 || !strings.Contains((*prompts)[0], "- WIP") || !strings.Contains((*prompts)[0], `"add b"`) {
+		t.Errorf("Expected the entries and other commits in the prompt, got %q", *prompts)
+	}
+
+	// A failed polish keeps the entries from the commits
+	fakeModel(t, dir, "not json")
+	if cfg, err = config.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if section, err = historySection(ctx, cfg, r, true); err != nil || !strings.Contains(section, "- add b") {
+		t.Errorf("Expected the unpolished entries, got %q, %v", section, err)
+	}
+
+	if section, err = historySection(ctx, cfg, changelogRange{from: "HEAD", to: "HEAD"}, false); err != nil || section != "" {
+		t.Errorf("Expected nothing for an empty range, got %q, %v", section, err)
+	}
+}
@@ -1,33 +1,34 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
 	"os/exec"
-	"regexp"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/gudlyf/GudCommit/golang/pkg/bedrock"
+	"github.com/gudlyf/GudCommit/golang/pkg/changelog"
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+	"github.com/gudlyf/GudCommit/golang/pkg/diff"
+	"github.com/gudlyf/GudCommit/golang/pkg/redact"
+	"github.com/gudlyf/GudCommit/golang/pkg/term"
 )
 
-// ChangelogEntry represents a changelog section
-type ChangelogEntry struct {
-	Added   []string `json:"added"`
-	Changed []string `json:"changed"`
-	Removed []string `json:"removed"`
-}
-
-// ChangelogResponse represents the JSON response from Bedrock
-type ChangelogResponse struct {
-	Changelog ChangelogEntry `json:"changelog"`
-}
+// status receives progress and messages meant for people; commands that
+// print their result on stdout move it to stderr
+var status io.Writer = os.Stdout
 
-// getGitDiff retrieves the diff between current branch and target branch
-func getGitDiff(targetBranch string) (string, error) {
-	cmd := exec.Command("git", "diff", targetBranch+"..HEAD")
+// getGitDiff retrieves the diff between two revisions
+func getGitDiff(ctx context.Context, from, to string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff", from+".."+to)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get git diff: %w", err)
@@ -35,104 +36,79 @@ func getGitDiff(targetBranch string) (string, error) {
 	return string(output), nil
 }
 
-// invokeBedrockModel invokes Bedrock directly using API key authentication
-func invokeBedrockModel(prompt, repoPath string) (string, error) {
-	client, err := bedrock.NewClient()
+// invokeModel invokes the configured model provider with the prompt template
+func invokeModel(ctx context.Context, cfg *config.Config, prompt, repoPath string) (string, error) {
+	// Filter, redact and, if it is too large for one request, summarise the diff
+	fitted, err := prepareDiff(ctx, cfg, prompt, repoPath)
 	if err != nil {
 		return "", err
 	}
 
 	// Create the prompt with the same template as before
-	fullPrompt := fmt.Sprintf(`Analyze the following git diff and generate changelog entries in the specified JSON format.
+	fullPrompt := fmt.Sprintf(`Analyze the following %s and generate changelog entries in the specified JSON format.
 
 Repository root: %s
 
-<git_diff>%s</git_diff>
+%s
 
 Respond with JSON matching this exact schema:
 
-{
-  "type": "object",
-  "properties": {
-    "changelog": {
-      "type": "object",
-      "properties": {
-        "added": {
-          "type": "array",
-          "items": {"type": "string"},
-          "description": "List of new features or additions"
-        },
-        "changed": {
-          "type": "array",
-          "items": {"type": "string"},
-          "description": "List of changes to existing functionality"
-        },
-        "removed": {
-          "type": "array",
-          "items": {"type": "string"},
-          "description": "List of removed features or functionality"
-        }
-      },
-      "required": ["added", "changed", "removed"],
-      "additionalProperties": false
-    }
-  },
-  "required": ["changelog"],
-  "additionalProperties": false
-}
+%s
 
 Rules:
 - Follow Keep a Changelog format (http://keepachangelog.com/)
 - Be concise and clear
 - Focus on WHAT changed and WHY
+%s
 - Do not include any explanatory text outside the JSON
-- Each category should contain meaningful entries`, repoPath, prompt)
+- Leave a category empty when nothing belongs in it`, fitted.Subject(), repoPath, fitted.Block(), cfg.Changelog.Schema(), cfg.Changelog.PromptRules())
 
-	return client.InvokeModel(fullPrompt, repoPath)
+	return generate(ctx, cfg, fullPrompt)
 }
 
-// parseChangelogResponse parses the response from Bedrock and returns formatted changelog entries
-func parseChangelogResponse(response string) (*ChangelogEntry, error) {
-	// Clean the response - remove any markdown formatting or extra text
-	cleanedResponse := regexp.MustCompile("```json\n?").ReplaceAllString(response, "")
-	cleanedResponse = regexp.MustCompile("```\n?").ReplaceAllString(cleanedResponse, "")
-	cleanedResponse = regexp.MustCompile("^[^{]*").ReplaceAllString(cleanedResponse, "")
-	cleanedResponse = regexp.MustCompile("[^}]*$").ReplaceAllString(cleanedResponse, "")
-	cleanedResponse = strings.TrimSpace(cleanedResponse)
+// generate sends prompt to the configured model provider
+func generate(ctx context.Context, cfg *config.Config, prompt string) (string, error) {
+	provider, err := bedrock.NewProvider(cfg.Config)
+	if err != nil {
+		return "", err
+	}
 
-	var changelogResp ChangelogResponse
-	if err := json.Unmarshal([]byte(cleanedResponse), &changelogResp); err != nil {
-		return nil, fmt.Errorf("failed to parse changelog response: %w", err)
+	// Print the response as it is generated so long runs show progress
+	if cfg.Stream {
+		return bedrock.GenerateStream(ctx, provider, prompt, bedrock.Options{}, func(delta string) {
+			fmt.Fprint(status, delta)
+		})
 	}
 
-	return &changelogResp.Changelog, nil
+	return provider.Generate(ctx, prompt, bedrock.Options{})
 }
 
-// formatChangelog formats the changelog entry for display
-func formatChangelog(changelog *ChangelogEntry) string {
-	var result strings.Builder
-
-	result.WriteString("## [Unreleased]\n\n")
-
-	if len(changelog.Added) > 0 {
-		result.WriteString("### Added\n")
-		for _, item := range changelog.Added {
-			result.WriteString(fmt.Sprintf("- %s\n", item))
-		}
-		result.WriteString("\n")
+// prepareDiff filters, redacts and budgets the diff with diff.Prepare,
+// summarising a diff that is too large with a provider of its own
+func prepareDiff(ctx context.Context, cfg *config.Config, diffText, repoPath string) (diff.Result, error) {
+	// Summaries run in parallel, so keep their progress off the spinner
+	summarizer, err := bedrock.NewProvider(cfg.Config)
+	if err != nil {
+		return diff.Result{}, err
 	}
+	summarizer.SetReporter(bedrock.SilentReporter{})
 
-	if len(changelog.Changed) > 0 {
-		result.WriteString("### Changed\n")
-		for _, item := range changelog.Changed {
-			result.WriteString(fmt.Sprintf("- %s\n", item))
-		}
-		result.WriteString("\n")
+	generate := func(ctx context.Context, prompt string) (string, error) {
+		return summarizer.Generate(ctx, prompt, bedrock.Options{})
 	}
+	return diff.Prepare(ctx, cfg.Diff, cfg.Redact, diffText, repoPath, generate, status)
+}
+
+// formatChangelog formats the changelog entry for display under heading,
+// e.g. "[Unreleased]" or "[1.2.0] - 2024-05-01", with its sections in order
+func formatChangelog(opts changelog.Options, entry changelog.Entry, heading string) string {
+	var result strings.Builder
+
+	result.WriteString("## " + heading + "\n\n")
 
-	if len(changelog.Removed) > 0 {
-		result.WriteString("### Removed\n")
-		for _, item := range changelog.Removed {
+	for _, name := range opts.Ordered(entry) {
+		result.WriteString("### " + name + "\n")
+		for _, item := range entry[name] {
 			result.WriteString(fmt.Sprintf("- %s\n", item))
 		}
 		result.WriteString("\n")
@@ -141,99 +117,238 @@ func formatChangelog(changelog *ChangelogEntry) string {
 	return result.String()
 }
 
-// run is the main function that orchestrates the changelog generation
-func run() error {
-
-	// Check command line arguments
-	if len(os.Args) < 2 {
-		return fmt.Errorf("usage: gudchangelog <target-branch>")
+// writeChangelog merges the generated sections into the changelog at path,
+// adding entries to its Unreleased section and keeping the rest of the file
+// as it is. A missing file is created with the Keep a Changelog header. It
+// returns the number of entries added.
+func writeChangelog(opts changelog.Options, path, generated string) (int, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		content, err = []byte(changelog.Header), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
 	}
 
-	targetBranch := os.Args[1]
+	file := changelog.ParseFile(string(content))
+	added := file.Merge(opts, changelog.ParseFile(generated))
+	if added == 0 {
+		return 0, nil
+	}
+	if err := os.WriteFile(path, []byte(file.String()), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return added, nil
+}
 
-	// Get repository root path for better context
-	repoRoot, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+// repoRoot returns the top-level directory of the repository, or "." when
+// it cannot be determined
+func repoRoot(ctx context.Context) string {
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel").Output()
 	if err != nil {
-		repoRoot = []byte(".")
+		return "."
 	}
-	repoPath := strings.TrimSpace(string(repoRoot))
+	return strings.TrimSpace(string(out))
+}
+
+// usageError is a command-line error that has already been reported along
+// with the usage text
+type usageError struct {
+	err error
+}
 
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+// usageFailure wraps a flag parsing error; flag.ErrHelp is passed through
+func usageFailure(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return usageError{err}
+}
+
+// subcommands maps a first argument to the command it runs; anything else
+// is the default changelog flow
+var subcommands = map[string]func(ctx context.Context, args []string) error{
+	"pr":      runPR,
+	"release": runRelease,
+}
+
+// generateSection generates and formats the changelog section for r. It
+// returns "" after explaining why when there is nothing to add.
+func generateSection(ctx context.Context, cfg *config.Config, repoPath string, r changelogRange) (string, error) {
 	// Get git diff
-	diffOutput, err := getGitDiff(targetBranch)
+	diffOutput, err := rangeDiff(ctx, r)
 	if err != nil {
-		return fmt.Errorf("failed to get git diff: %w", err)
+		return "", err
 	}
 
 	// Check if diffOutput is empty
 	if strings.TrimSpace(diffOutput) == "" {
-		fmt.Println(">> No changes found between current branch and", targetBranch)
-		return nil
+		fmt.Println(">> No changes found in", r)
+		return "", nil
 	}
 
 	// Escape backslashes for JSON
 	diffOutput = strings.ReplaceAll(diffOutput, "\\", "\\\\")
 
-	// Generate changelog
-	fmt.Println("🤖 Generating changelog...")
-	completion, err := invokeBedrockModel(diffOutput, repoPath)
+	completion, err := invokeModel(ctx, cfg, diffOutput, repoPath)
 	if err != nil {
-		return fmt.Errorf("failed to invoke Bedrock model: %w", err)
+		var overCap *diff.OverCapError
+		if errors.As(err, &overCap) {
+			fmt.Printf("❌ %v\n", err)
+			return "", nil
+		}
+		var secrets *redact.FoundError
+		if errors.As(err, &secrets) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to invoke model: %w", err)
 	}
 
 	if completion == "" {
 		fmt.Println("Sorry. No changelog could be generated.")
-		return nil
+		return "", nil
 	}
 
 	// Parse response
-	changelog, err := parseChangelogResponse(completion)
+	entry, err := cfg.Changelog.Parse(completion)
 	if err != nil {
 		fmt.Printf("Warning: Failed to parse structured response: %v\n", err)
 		// Fallback to raw response
 		fmt.Println("Raw response:")
 		fmt.Println(completion)
-		return nil
+		return "", nil
+	}
+
+	return formatChangelog(cfg.Changelog, entry, r.heading), nil
+}
+
+// run is the main function that orchestrates the changelog generation
+func run(ctx context.Context) error {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			return cmd(ctx, os.Args[2:])
+		}
+	}
+
+	showConfig := flag.Bool("show-config", false, "print the effective configuration and where each value came from")
+	from := flag.String("from", "", "start of the range: a tag, branch or commit (default: the last tag before --to)")
+	to := flag.String("to", "HEAD", "end of the range")
+	allTags := flag.Bool("all-tags", false, "generate a section for every tag, and for the changes since the last one, to backfill a changelog")
+	commits := flag.Bool("commits", false, "build the entries from the conventional commits in the range instead of asking the model about the diff")
+	polish := flag.Bool("polish", false, "with --commits, have the model polish the entries and summarise commits that are not conventional")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: gudchangelog [flags] [<target-branch>]\n       gudchangelog pr [flags] <target-branch>\n       gudchangelog release [flags] <version>\n\n"+
+			"Generate changelog entries for the changes between target-branch and HEAD, or between\n"+
+			"--from and --to. Without either, the range starts at the last tag.\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Get repository root path for config lookup and better context
+	repoPath := repoRoot(ctx)
+
+	cfg, err := config.Load(repoPath)
+	if err != nil {
+		return err
+	}
+	if *showConfig {
+		return cfg.Print(os.Stdout)
+	}
+
+	// Check command line arguments
+	var usage error
+	switch {
+	case flag.NArg() > 1:
+		usage = fmt.Errorf("expected at most one target branch")
+	case flag.NArg() == 1 && *from != "":
+		usage = fmt.Errorf("give either a target branch or --from, not both")
+	case *allTags && (flag.NArg() > 0 || *from != ""):
+		usage = fmt.Errorf("--all-tags cannot be combined with a target branch or --from")
+	case *polish && !*commits:
+		usage = fmt.Errorf("--polish only applies with --commits")
+	}
+	if usage != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		flag.Usage()
+		return usageError{usage}
+	}
+	if flag.NArg() == 1 {
+		*from = flag.Arg(0)
 	}
 
-	// Format and display the changelog
-	formattedChangelog := formatChangelog(changelog)
+	var ranges []changelogRange
+	if *allTags {
+		if ranges, err = tagRanges(ctx, *to); err != nil {
+			return err
+		}
+	} else {
+		r, err := resolveRange(ctx, *from, *to)
+		if err != nil {
+			return err
+		}
+		if r.from == "" {
+			fmt.Printf(">> No tags found before %s; describing its whole history\n", *to)
+		}
+		ranges = []changelogRange{r}
+	}
+
+	var sections []string
+	for i, r := range ranges {
+		label := "🤖 Generating changelog for"
+		if *commits && !*polish {
+			label = "📜 Collecting changelog entries from the commits in"
+		}
+		if len(ranges) > 1 {
+			fmt.Printf("[%d/%d] %s %s...\n", i+1, len(ranges), label, r)
+		} else {
+			fmt.Printf("%s %s...\n", label, r)
+		}
+		var section string
+		if *commits {
+			section, err = historySection(ctx, cfg, r, *polish)
+		} else {
+			section, err = generateSection(ctx, cfg, repoPath, r)
+		}
+		if err != nil {
+			return err
+		}
+		if section != "" {
+			sections = append(sections, section)
+		}
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+	formattedChangelog := strings.Join(sections, "")
 
 	fmt.Println()
 	fmt.Println("📝 Generated changelog:")
 	fmt.Println("========================")
 	fmt.Println(formattedChangelog)
 
-	// Ask if user wants to prepend to CHANGELOG.md
-	fmt.Print("Prepend this content to CHANGELOG.md? (y/n): ")
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+	// Ask if user wants to add the entries to CHANGELOG.md
+	fmt.Print("Add these entries to CHANGELOG.md? (y/n): ")
+	response, err := term.ReadLine(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to read user input: %w", err)
 	}
 
-	response = strings.TrimSpace(strings.ToLower(response))
+	response = strings.ToLower(response)
 	if response == "y" || response == "yes" {
-		// Write to CHANGELOG.md
 		changelogFile := "CHANGELOG.md"
-
-		// Read existing content if file exists
-		var existingContent string
-		if content, err := os.ReadFile(changelogFile); err == nil {
-			existingContent = string(content)
-		}
-
-		// Create new content with generated changelog at the top
-		newContent := formattedChangelog
-		if existingContent != "" {
-			newContent += "\n---\n\n" + existingContent
+		added, err := writeChangelog(cfg.Changelog, changelogFile, formattedChangelog)
+		if err != nil {
+			return err
 		}
-
-		// Write to file
-		if err := os.WriteFile(changelogFile, []byte(newContent), 0644); err != nil {
-			return fmt.Errorf("failed to write CHANGELOG.md: %w", err)
+		if added == 0 {
+			fmt.Printf("✅ %s already lists these entries\n", changelogFile)
+		} else {
+			fmt.Printf("✅ Added %d entries to %s\n", added, changelogFile)
 		}
-
-		fmt.Printf("✅ Changelog written to %s\n", changelogFile)
 	} else {
 		fmt.Println("Changelog generation completed.")
 	}
@@ -242,7 +357,24 @@ func run() error {
 }
 
 func main() {
-	if err := run(); err != nil {
+	// Cancel in-flight work on Ctrl-C or SIGTERM instead of dying mid-spinner
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx); err != nil {
+		var usage usageError
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return
+		case errors.As(err, &usage):
+			// The error and usage have already been printed
+			os.Exit(2)
+		}
+		if ctx.Err() != nil {
+			stop()
+			fmt.Fprintln(status, "\n>> Canceled.")
+			os.Exit(term.ExitInterrupted)
+		}
 		log.Fatalf(">> %v", err)
 	}
 }
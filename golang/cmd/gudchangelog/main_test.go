@@ -2,7 +2,11 @@ package main
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/changelog"
 )
 
 func TestMainFunction(t *testing.T) {
@@ -66,3 +70,30 @@ func BenchmarkMainFunction(b *testing.B) {
 		// you would benchmark the actual main function logic
 	}
 }
+
+func TestWriteChangelog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	opts := changelog.Options{}
+
+	added, err := writeChangelog(opts, path, "## [Unreleased]\n\n### Added\n- First\n\n")
+	if err != nil || added != 1 {
+		t.Fatalf("Expected one entry added, got %d, %v", added, err)
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != changelog.Header+"## [Unreleased]\n\n### Added\n- First\n" {
+		t.Errorf("Unexpected new changelog:\n%s", content)
+	}
+
+	// A second run adds to Unreleased instead of another section
+	if added, err = writeChangelog(opts, path, "## [Unreleased]\n\n### Added\n- First\n- Second\n\n"); err != nil || added != 1 {
+		t.Fatalf("Expected one entry added, got %d, %v", added, err)
+	}
+	content, _ = os.ReadFile(path)
+	if strings.Count(string(content), "## [Unreleased]") != 1 || !strings.HasSuffix(string(content), "- First\n- Second\n") {
+		t.Errorf("Unexpected merged changelog:\n%s", content)
+	}
+
+	if added, err = writeChangelog(opts, path, "## [Unreleased]\n\n### Added\n- Second\n\n"); err != nil || added != 0 {
+		t.Errorf("Expected nothing added, got %d, %v", added, err)
+	}
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+	"github.com/gudlyf/GudCommit/golang/pkg/diff"
+	"github.com/gudlyf/GudCommit/golang/pkg/redact"
+)
+
+// prTemplates are the places GitHub looks for a pull request template,
+// relative to the repository root
+var prTemplates = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+}
+
+// PRSection is the content generated for one heading of a template
+type PRSection struct {
+	Heading string `json:"heading"`
+	Content string `json:"content"`
+}
+
+// PRResponse represents the JSON response for a pull request description
+type PRResponse struct {
+	Title           string      `json:"title"`
+	Summary         string      `json:"summary"`
+	Motivation      string      `json:"motivation"`
+	Changes         []string    `json:"changes"`
+	Testing         string      `json:"testing"`
+	BreakingChanges []string    `json:"breaking_changes"`
+	Sections        []PRSection `json:"sections"`
+}
+
+// templateHeading is a Markdown heading in a pull request template and the
+// text it introduces
+type templateHeading struct {
+	line string
+	text string
+	body string
+}
+
+// headingPattern matches an ATX Markdown heading
+var headingPattern = regexp.MustCompile(`^#{1,6}[ \t]+(.*?)[ \t#]*$`)
+
+// prTemplate is a pull request template split at its headings
+type prTemplate struct {
+	// text is the template as written
+	text string
+	// preamble is the text before the first heading
+	preamble string
+	headings []templateHeading
+}
+
+// parsePRTemplate splits a template at its headings, ignoring lines inside
+// fenced code blocks
+func parsePRTemplate(text string) prTemplate {
+	t := prTemplate{text: text}
+	var body []string
+	flush := func() {
+		joined := strings.Join(body, "\n")
+		if len(t.headings) == 0 {
+			t.preamble = joined
+		} else {
+			t.headings[len(t.headings)-1].body = joined
+		}
+		body = nil
+	}
+	fenced := false
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil && !fenced {
+			flush()
+			t.headings = append(t.headings, templateHeading{line: line, text: m[1]})
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+	return t
+}
+
+// fill returns the template with the body under each heading replaced by the
+// generated section of the same name; headings without one keep their text
+func (t prTemplate) fill(sections []PRSection) string {
+	content := map[string]string{}
+	for _, s := range sections {
+		if c := strings.TrimSpace(s.Content); c != "" {
+			content[normalizeHeading(s.Heading)] = c
+		}
+	}
+	var b strings.Builder
+	if strings.TrimSpace(t.preamble) != "" {
+		b.WriteString(strings.TrimSpace(t.preamble) + "\n\n")
+	}
+	for _, h := range t.headings {
+		b.WriteString(h.line + "\n\n")
+		body, ok := content[normalizeHeading(h.text)]
+		if !ok {
+			body = strings.TrimSpace(h.body)
+		}
+		if body != "" {
+			b.WriteString(body + "\n\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// normalizeHeading makes headings comparable regardless of case, spacing and
+// decoration such as emoji
+func normalizeHeading(heading string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(heading), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}), " ")
+}
+
+// findPRTemplate returns the path of the repository's pull request template,
+// or "" when it has none
+func findPRTemplate(repoPath string) string {
+	for _, name := range prTemplates {
+		path := filepath.Join(repoPath, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// runPR generates a pull request title and description for the current
+// branch
+func runPR(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("gudchangelog pr", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gudchangelog pr [flags] <target-branch>\n\n"+
+			"Generate a pull request title and Markdown description from the changes and commits on\n"+
+			"the current branch since it left target-branch. When the repository has a pull request\n"+
+			"template its headings are filled in.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	var output, templatePath string
+	var noTemplate bool
+	fs.StringVar(&output, "output", "", "write the title and description to this file instead of stdout")
+	fs.StringVar(&output, "o", "", "shorthand for --output")
+	fs.StringVar(&templatePath, "template", "", "pull request template to fill in (default: the repository's, e.g. .github/pull_request_template.md)")
+	fs.BoolVar(&noTemplate, "no-template", false, "ignore the repository's pull request template")
+	if err := fs.Parse(args); err != nil {
+		return usageFailure(err)
+	}
+	if fs.NArg() != 1 {
+		err := fmt.Errorf("expected one target branch")
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return usageError{err}
+	}
+	target := fs.Arg(0)
+
+	// Keep stdout for the description
+	status = os.Stderr
+
+	repoPath := repoRoot(ctx)
+	cfg, err := config.Load(repoPath)
+	if err != nil {
+		return err
+	}
+
+	var template *prTemplate
+	if !noTemplate {
+		if templatePath == "" {
+			templatePath = findPRTemplate(repoPath)
+		}
+		if templatePath != "" {
+			data, err := os.ReadFile(templatePath)
+			if err != nil {
+				return fmt.Errorf("failed to read pull request template: %w", err)
+			}
+			t := parsePRTemplate(string(data))
+			if len(t.headings) > 0 {
+				template = &t
+				fmt.Fprintf(status, "📋 Filling in %s\n", templatePath)
+			}
+		}
+	}
+
+	// The merge base is what the pull request will show
+	diffOutput, err := gitText(ctx, "diff", target+"...HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to get git diff: %w", err)
+	}
+	if strings.TrimSpace(diffOutput) == "" {
+		fmt.Fprintln(status, ">> No changes found between current branch and", target)
+		return nil
+	}
+	commitLog, err := gitText(ctx, "log", "--reverse", "--no-merges", "--format=- %s%n%w(0,2,2)%b", target+"..HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to get git log: %w", err)
+	}
+
+	// Escape backslashes for JSON
+	diffOutput = strings.ReplaceAll(diffOutput, "\\", "\\\\")
+
+	fmt.Fprintln(status, "🤖 Generating pull request description...")
+	completion, err := invokePRModel(ctx, cfg, diffOutput, commitLog, repoPath, template)
+	if err != nil {
+		var overCap *diff.OverCapError
+		if errors.As(err, &overCap) {
+			fmt.Fprintf(status, "❌ %v\n", err)
+			return nil
+		}
+		var secrets *redact.FoundError
+		if errors.As(err, &secrets) {
+			return err
+		}
+		return fmt.Errorf("failed to invoke model: %w", err)
+	}
+	if completion == "" {
+		fmt.Fprintln(status, "Sorry. No pull request description could be generated.")
+		return nil
+	}
+
+	pr, err := parsePRResponse(completion)
+	if err != nil {
+		fmt.Fprintf(status, "Warning: Failed to parse structured response: %v\n", err)
+		fmt.Fprintln(status, "Raw response:")
+		fmt.Println(completion)
+		return nil
+	}
+	if !validTitle(cfg, pr.Title) {
+		fmt.Fprintf(status, "⚠ :: The title does not follow the configured conventional commit format: %s\n", pr.Title)
+	}
+
+	result := pr.Title + "\n\n" + formatPR(pr, template)
+	if output == "" {
+		fmt.Fprintln(status)
+		fmt.Print(result)
+		return nil
+	}
+	if err := os.WriteFile(output, []byte(result), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	fmt.Fprintf(status, "✅ Pull request description written to %s\n", output)
+	return nil
+}
+
+// invokePRModel asks the model for a pull request title and description
+// covering the branch's diff and commit log
+func invokePRModel(ctx context.Context, cfg *config.Config, diffText, commitLog, repoPath string, template *prTemplate) (string, error) {
+	fitted, err := prepareDiff(ctx, cfg, diffText, repoPath)
+	if err != nil {
+		return "", err
+	}
+	commitLog, err = redactText(cfg, commitLog)
+	if err != nil {
+		return "", err
+	}
+
+	schema := `{
+  "type": "object",
+  "properties": {
+    "title": {
+      "type": "string",
+      "description": "Pull request title as a conventional commit header"
+    },
+    "summary": {
+      "type": "string",
+      "description": "One or two sentences on what the pull request does"
+    },
+    "motivation": {
+      "type": "string",
+      "description": "Why the change is needed"
+    },
+    "changes": {
+      "type": "array",
+      "items": {"type": "string"},
+      "description": "The notable changes, one per item"
+    },
+    "testing": {
+      "type": "string",
+      "description": "How the change is tested or can be verified"
+    },
+    "breaking_changes": {
+      "type": "array",
+      "items": {"type": "string"},
+      "description": "What breaks and how to migrate; empty when nothing breaks"
+    }
+  },
+  "required": ["title", "summary", "motivation", "changes", "testing", "breaking_changes"],
+  "additionalProperties": false
+}`
+	sectionRule := "- Write each field in Markdown without headings"
+	if template != nil {
+		schema = `{
+  "type": "object",
+  "properties": {
+    "title": {
+      "type": "string",
+      "description": "Pull request title as a conventional commit header"
+    },
+    "sections": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "heading": {"type": "string", "description": "A heading of the template, exactly as written"},
+          "content": {"type": "string", "description": "Markdown to put under the heading"}
+        },
+        "required": ["heading", "content"],
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["title", "sections"],
+  "additionalProperties": false
+}`
+		headings := make([]string, len(template.headings))
+		for i, h := range template.headings {
+			headings[i] = h.text
+		}
+		sectionRule = fmt.Sprintf(`- Fill in every heading of this pull request template, in order: %s
+- Follow what the template asks for under each heading; keep its checklists, ticking only items the changes show are done
+- Leave content empty for headings the changes give nothing to say about
+
+Pull request template:
+
+%s`, strings.Join(headings, ", "), strings.TrimSpace(template.text))
+	}
+
+	prompt := fmt.Sprintf(`Analyze the following %s and commits of a branch and write a pull request title and description in the specified JSON format.
+
+Repository root: %s
+
+Commits on the branch, oldest first:
+
+%s
+
+%s
+
+Respond with JSON matching this exact schema:
+
+%s
+
+Rules:
+- Title: %s, summarising the whole branch as if it were squash-merged, one line
+- Types: %s
+- Describe WHAT changed and WHY for a reviewer; do not repeat the diff line by line
+- Only mention tests, issues and breaking changes that the diff or commits show; never invent them
+%s
+- Do not include any explanatory text outside the JSON`,
+		fitted.Subject(), repoPath, strings.TrimSpace(commitLog), fitted.Block(), schema,
+		prTitleFormat(cfg), strings.Join(cfg.Commit.Types, ", "), sectionRule)
+
+	return generate(ctx, cfg, prompt)
+}
+
+// prTitleFormat returns the header format the title should follow
+func prTitleFormat(cfg *config.Config) string {
+	if cfg.Commit.UsesScope() {
+		return "type(scope): description, with the scope left out when the branch spans several components"
+	}
+	return "type: description"
+}
+
+// validTitle reports whether title passes the commit-msg hook's checks, as
+// it usually becomes the header of the squashed commit
+func validTitle(cfg *config.Config, title string) bool {
+	return len(cfg.Commit.LintMessage(title)) == 0
+}
+
+// parsePRResponse parses the model's JSON response
+func parsePRResponse(response string) (*PRResponse, error) {
+	cleaned := regexp.MustCompile("```(json)?\n?").ReplaceAllString(response, "")
+	start, end := strings.Index(cleaned, "{"), strings.LastIndex(cleaned, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in response")
+	}
+	var pr PRResponse
+	if err := json.Unmarshal([]byte(cleaned[start:end+1]), &pr); err != nil {
+		return nil, fmt.Errorf("failed to parse pull request response: %w", err)
+	}
+	pr.Title = strings.TrimSpace(pr.Title)
+	if pr.Title == "" {
+		return nil, fmt.Errorf("response has no title")
+	}
+	return &pr, nil
+}
+
+// formatPR renders the description, filling template when there is one
+func formatPR(pr *PRResponse, template *prTemplate) string {
+	if template != nil {
+		return template.fill(pr.Sections)
+	}
+
+	var b strings.Builder
+	section := func(heading, text string) {
+		if text = strings.TrimSpace(text); text != "" {
+			fmt.Fprintf(&b, "## %s\n\n%s\n\n", heading, text)
+		}
+	}
+	list := func(items []string) string {
+		var lines []string
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				lines = append(lines, "- "+item)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	section("Summary", pr.Summary)
+	section("Motivation", pr.Motivation)
+	section("Changes", list(pr.Changes))
+	section("Testing", pr.Testing)
+	section("Breaking changes", list(pr.BreakingChanges))
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// redactText redacts secrets in text that goes to the model alongside the
+// diff, such as commit messages
+func redactText(cfg *config.Config, text string) (string, error) {
+	text, findings, err := redact.Apply(cfg.Redact, text)
+	if err != nil {
+		return "", err
+	}
+	if len(findings) > 0 {
+		fmt.Fprintf(status, "🔒 :: Redacted %d possible secret(s) in the commit messages before sending\n", len(findings))
+	}
+	return text, nil
+}
+
+// gitText runs git and returns its output
+func gitText(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
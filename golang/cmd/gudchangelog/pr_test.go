@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+)
+
+// newRepo creates a repository with one commit on main and changes into it
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig-none"))
+	gitRun(t, dir, "init", "-q", "-b", "main")
+	gitRun(t, dir, "config", "user.name", "Test")
+	gitRun(t, dir, "config", "user.email", "test@example.com")
+	if err := os.WriteFile(filepath.Join(dir, ".git", "info", "exclude"), []byte(".gudcommit.json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, dir, "a.txt", "a\n", "feat: add a")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// commitFile writes name and commits it with message
+func commitFile(t *testing.T, dir, name, content, message string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, dir, "add", name)
+	gitRun(t, dir, "commit", "-q", "-m", message)
+}
+
+// fakeModel serves OpenAI-style chat completions with content, points the
+// repository's configuration at it and returns the prompts it receives
+func fakeModel(t *testing.T, dir, content string) *[]string {
+	t.Helper()
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if n := len(req.Messages); n > 0 {
+			prompts = append(prompts, req.Messages[n-1].Content)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := `{"provider": "openai", "endpoint": "` + server.URL + `", "progress": "silent", "retry": {"max_attempts": 1}}`
+	if err := os.WriteFile(filepath.Join(dir, ".gudcommit.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return &prompts
+}
+
+// quiet discards status messages for the rest of the test
+func quiet(t *testing.T) {
+	old := status
+	status = io.Discard
+	t.Cleanup(func() { status = old })
+}
+
+const testTemplate = `<!-- Thanks for contributing! -->
+## What does this change?
+
+Describe the change.
+
+## How was it tested?
+
+- [ ] Unit tests
+
+` + "```" + `
+# not a heading
+` + "```" + `
+
+## Checklist
+- [ ] Docs updated
+`
+
+func TestParsePRTemplate(t *testing.T) {
+	tmpl := parsePRTemplate(testTemplate)
+	var headings []string
+	for _, h := range tmpl.headings {
+		headings = append(headings, h.text)
+	}
+	if !reflect.DeepEqual(headings, []string{"What does this change?", "How was it tested?", "Checklist"}) {
+		t.Fatalf("Unexpected headings %q", headings)
+	}
+	if strings.TrimSpace(tmpl.preamble) != "<!-- Thanks for contributing! -->" {
+		t.Errorf("Unexpected preamble %q", tmpl.preamble)
+	}
+
+	filled := tmpl.fill([]PRSection{
+		{Heading: "what does this change", Content: "Adds retries.\n"},
+		{Heading: "How was it tested?", Content: "- [x] Unit tests"},
+		{Heading: "Checklist", Content: " "},
+	})
+	expected := `<!-- Thanks for contributing! -->
+
+## What does this change?
+
+Adds retries.
+
+## How was it tested?
+
+- [x] Unit tests
+
+## Checklist
+
+- [ ] Docs updated
+`
+	if filled != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, filled)
+	}
+}
+
+func TestFormatPR(t *testing.T) {
+	pr, err := parsePRResponse("```json\n" + `{"title": " feat(api): add retries ", "summary": "Retry failed requests.", "motivation": "",
+		"changes": ["Add a retry loop", " "], "testing": "Unit tests cover the backoff.", "breaking_changes": []}` + "\n```")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pr.Title != "feat(api): add retries" {
+		t.Errorf("Unexpected title %q", pr.Title)
+	}
+	expected := `## Summary
+
+Retry failed requests.
+
+## Changes
+
+- Add a retry loop
+
+## Testing
+
+Unit tests cover the backoff.
+`
+	if got := formatPR(pr, nil); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	for _, response := range []string{"not json", `{"summary": "no title"}`} {
+		if _, err := parsePRResponse(response); err == nil {
+			t.Errorf("Expected an error for %q", response)
+		}
+	}
+}
+
+func TestRunPR(t *testing.T) {
+	dir := newRepo(t)
+	quiet(t)
+	gitRun(t, dir, "checkout", "-q", "-b", "retries")
+	commitFile(t, dir, "retry.go", "package retry\n", "feat(retry): add a retry loop")
+	gitRun(t, dir, "checkout", "-q", "main")
+	commitFile(t, dir, "other.txt", "other\n", "docs: unrelated change on main")
+	gitRun(t, dir, "checkout", "-q", "retries")
+
+	os.MkdirAll(filepath.Join(dir, ".github"), 0o755)
+	os.WriteFile(filepath.Join(dir, ".github", "pull_request_template.md"), []byte(testTemplate), 0o644)
+	prompts := fakeModel(t, dir, `{"title": "feat(retry): retry failed requests", "sections": [{"heading": "What does this change?", "content": "Adds a retry loop."}]}`)
+
+	output := filepath.Join(dir, "pr.md")
+	if err := runPR(context.Background(), []string{"-o", output, "main"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "feat(retry): retry failed requests\n\n<!-- Thanks") ||
+		!strings.Contains(string(data), "## What does this change?\n\nAdds a retry loop.\n") ||
+		!strings.Contains(string(data), "- [ ] Unit tests") {
+		t.Errorf("Unexpected description:\n%s", data)
+	}
+
+	if len(*prompts) != 1 {
+		t.Fatalf("Expected one request, got %d", len(*prompts))
+	}
+	prompt := (*prompts)[0]
+	for _, want := range []string{"- feat(retry): add a retry loop", "retry.go", "What does this change?, How was it tested?, Checklist"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt:\n%s", want, prompt)
+		}
+	}
+	// Changes on the target branch are not part of the pull request
+	if strings.Contains(prompt, "other.txt") || strings.Contains(prompt, "unrelated change") {
+		t.Errorf("Expected only the branch's changes in the prompt:\n%s", prompt)
+	}
+
+	if err := runPR(context.Background(), []string{"a", "b"}); err == nil {
+		t.Error("Expected a usage error for two branches")
+	}
+}
+
+func TestValidTitle(t *testing.T) {
+	cfg := config.Defaults()
+	for title, valid := range map[string]bool{
+		"feat(api): add retries": true,
+		"feat: add retries":      true,
+		"fix!: drop the v1 API":  true,
+		"Add retries":            false,
+		"feature: add retries":   false,
+		"feat:add retries":       false,
+	} {
+		if got := validTitle(cfg, title); got != valid {
+			t.Errorf("validTitle(%q) = %v, expected %v", title, got, valid)
+		}
+	}
+
+	cfg.Commit.ScopeRequired = true
+	if validTitle(cfg, "feat: add retries") {
+		t.Error("Expected a title without a scope to fail when scopes are required")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/diff"
+)
+
+// unreleased is the heading of changes that are not in a tagged release
+const unreleased = "[Unreleased]"
+
+// changelogRange is a revision range and the changelog heading its changes
+// go under
+type changelogRange struct {
+	// from is empty for a range that starts at the root of the history
+	from    string
+	to      string
+	heading string
+}
+
+// String returns the range as git would write it
+func (r changelogRange) String() string {
+	if r.from == "" {
+		return r.to
+	}
+	return r.from + ".." + r.to
+}
+
+// resolveRange returns the range from..to. Without from it starts at the
+// last tag before to, or at the root when there is none.
+func resolveRange(ctx context.Context, from, to string) (changelogRange, error) {
+	if _, err := gitText(ctx, "rev-parse", "--verify", "--quiet", to+"^{commit}"); err != nil {
+		return changelogRange{}, fmt.Errorf("unknown revision %s", to)
+	}
+	if from == "" {
+		from = previousTag(ctx, to)
+	} else if _, err := gitText(ctx, "rev-parse", "--verify", "--quiet", from+"^{commit}"); err != nil {
+		return changelogRange{}, fmt.Errorf("unknown revision %s", from)
+	}
+	return changelogRange{from: from, to: to, heading: releaseHeading(ctx, to)}, nil
+}
+
+// tagRanges returns a range for every tag reachable from to, each starting
+// at the tag before it, newest first, followed at the top by the untagged
+// changes up to to when there are any
+func tagRanges(ctx context.Context, to string) ([]changelogRange, error) {
+	if _, err := gitText(ctx, "rev-parse", "--verify", "--quiet", to+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown revision %s", to)
+	}
+	out, err := gitText(ctx, "for-each-ref", "--sort=-creatordate", "--merged="+to, "--format=%(refname:short)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var ranges []changelogRange
+	if tagAt(ctx, to) == "" {
+		ranges = append(ranges, changelogRange{from: previousTag(ctx, to), to: to, heading: unreleased})
+	}
+	// A commit with several tags gets one section, under the tag tagAt picks
+	seen := map[string]bool{}
+	for _, tag := range strings.Fields(out) {
+		tag = tagAt(ctx, tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		ranges = append(ranges, changelogRange{from: previousTag(ctx, tag), to: tag, heading: releaseHeading(ctx, tag)})
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no tags found in the history of %s", to)
+	}
+	return ranges, nil
+}
+
+// tagAt returns the tag pointing at rev, or "" when there is none. Of
+// several tags it picks the highest version.
+func tagAt(ctx context.Context, rev string) string {
+	out, err := gitText(ctx, "tag", "--points-at", rev+"^{commit}", "--sort=-version:refname")
+	if err != nil {
+		return ""
+	}
+	tags := strings.Fields(out)
+	for _, tag := range tags {
+		if isVersion(tag) {
+			return tag
+		}
+	}
+	if len(tags) > 0 {
+		return tags[0]
+	}
+	return ""
+}
+
+// previousTag returns the most recent tag before rev, not counting a tag on
+// rev itself, or "" when there is none
+func previousTag(ctx context.Context, rev string) string {
+	if tagAt(ctx, rev) != "" {
+		rev += "^"
+	}
+	tag, err := gitText(ctx, "describe", "--tags", "--abbrev=0", rev)
+	if err != nil {
+		return ""
+	}
+	return tagAt(ctx, strings.TrimSpace(tag))
+}
+
+// releaseHeading returns "[version] - date" when rev is tagged, using the
+// tag without a leading "v" and the date it was made, and "[Unreleased]"
+// otherwise
+func releaseHeading(ctx context.Context, rev string) string {
+	tag := tagAt(ctx, rev)
+	if tag == "" {
+		return unreleased
+	}
+	date, err := gitText(ctx, "for-each-ref", "--format=%(creatordate:short)", "refs/tags/"+tag)
+	if err != nil || strings.TrimSpace(date) == "" {
+		return fmt.Sprintf("[%s]", tagVersion(tag))
+	}
+	return fmt.Sprintf("[%s] - %s", tagVersion(tag), strings.TrimSpace(date))
+}
+
+// tagVersion returns the version a tag names, e.g. 1.2.0 for v1.2.0
+func tagVersion(tag string) string {
+	if len(tag) > 1 && (tag[0] == 'v' || tag[0] == 'V') && tag[1] >= '0' && tag[1] <= '9' {
+		return tag[1:]
+	}
+	return tag
+}
+
+// isVersion reports whether tag names a version, such as v1.2.0 or 1.2
+func isVersion(tag string) bool {
+	version := tagVersion(tag)
+	return version != "" && version[0] >= '0' && version[0] <= '9'
+}
+
+// rangeDiff returns the diff of the changes in r; a range from the root is
+// diffed against the empty tree
+func rangeDiff(ctx context.Context, r changelogRange) (string, error) {
+	from := r.from
+	if from == "" {
+		from = diff.EmptyTree
+	}
+	return getGitDiff(ctx, from, r.to)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+)
+
+// taggedRepo creates a history of a.txt (v1.0.0), b.txt (v1.1.0) and an
+// untagged c.txt
+func taggedRepo(t *testing.T) string {
+	dir := newRepo(t)
+	gitRun(t, dir, "tag", "-a", "-m", "1.0.0", "v1.0.0")
+	commitFile(t, dir, "b.txt", "b\n", "feat: add b")
+	gitRun(t, dir, "tag", "v1.1.0")
+	gitRun(t, dir, "tag", "also-1.1.0")
+	commitFile(t, dir, "c.txt", "c\n", "fix: add c")
+	return dir
+}
+
+func TestResolveRange(t *testing.T) {
+	dir := taggedRepo(t)
+	ctx := context.Background()
+	date := strings.TrimSpace(gitRun(t, dir, "log", "-1", "--format=%cs"))
+
+	tests := []struct {
+		from, to string
+		expected changelogRange
+	}{
+		{to: "HEAD", expected: changelogRange{from: "v1.1.0", to: "HEAD", heading: unreleased}},
+		{to: "v1.1.0", expected: changelogRange{from: "v1.0.0", to: "v1.1.0", heading: "[1.1.0] - " + date}},
+		{to: "v1.0.0", expected: changelogRange{to: "v1.0.0", heading: "[1.0.0] - " + date}},
+		{from: "v1.0.0", to: "HEAD", expected: changelogRange{from: "v1.0.0", to: "HEAD", heading: unreleased}},
+	}
+	for _, tt := range tests {
+		got, err := resolveRange(ctx, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s..%s: %v", tt.from, tt.to, err)
+		}
+		if got != tt.expected {
+			t.Errorf("Expected %+v for %s..%s, got %+v", tt.expected, tt.from, tt.to, got)
+		}
+	}
+
+	for _, bad := range [][2]string{{"", "no-such-tag"}, {"no-such-tag", "HEAD"}} {
+		if _, err := resolveRange(ctx, bad[0], bad[1]); err == nil {
+			t.Errorf("Expected an error for %s..%s", bad[0], bad[1])
+		}
+	}
+}
+
+func TestTagRanges(t *testing.T) {
+	dir := taggedRepo(t)
+	ctx := context.Background()
+
+	ranges, err := tagRanges(ctx, "HEAD")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var got []string
+	for _, r := range ranges {
+		got = append(got, r.String()+" "+strings.SplitN(r.heading, " ", 2)[0])
+	}
+	// The second tag on v1.1.0 does not get a section of its own
+	expected := []string{"v1.1.0..HEAD [Unreleased]", "v1.0.0..v1.1.0 [1.1.0]", "v1.0.0 [1.0.0]"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	// The root range holds the whole history up to the first tag
+	diff, err := rangeDiff(ctx, ranges[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "+++ b/a.txt") || strings.Contains(diff, "b.txt") {
+		t.Errorf("Unexpected diff for the first release:\n%s", diff)
+	}
+
+	// Without untagged changes there is no Unreleased section
+	if ranges, err = tagRanges(ctx, "v1.1.0"); err != nil || len(ranges) != 2 {
+		t.Errorf("Expected two releases up to v1.1.0, got %+v, %v", ranges, err)
+	}
+
+	gitRun(t, dir, "tag", "-d", "v1.0.0", "v1.1.0", "also-1.1.0")
+	if _, err := tagRanges(ctx, "HEAD"); err == nil {
+		t.Error("Expected an error without tags")
+	}
+}
+
+func TestGenerateSection(t *testing.T) {
+	dir := taggedRepo(t)
+	quiet(t)
+	prompts := fakeModel(t, dir, `{"changelog": {"added": ["Add b"], "changed": [], "removed": []}}`)
+	cfg, err := config.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	section, err := generateSection(context.Background(), cfg, dir, changelogRange{from: "v1.0.0", to: "v1.1.0", heading: "[1.1.0] - 2024-05-01"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if section != "## [1.1.0] - 2024-05-01\n\n### Added\n- Add b\n\n" {
+		t.Errorf("Unexpected section:\n%s", section)
+	}
+	if len(*prompts) != 1 || !strings.Contains((*prompts)[0], "+++ b/b.txt") || strings.Contains((*prompts)[0], "c.txt") {
+		t.Errorf("Expected only the changes in the range in the prompt, got %q", *prompts)
+	}
+
+	// An empty range adds nothing without asking the model
+	if section, err = generateSection(context.Background(), cfg, dir, changelogRange{from: "v1.1.0", to: "also-1.1.0"}); err != nil || section != "" {
+		t.Errorf("Expected nothing for an empty range, got %q, %v", section, err)
+	}
+	if len(*prompts) != 1 {
+		t.Errorf("Expected no request for an empty range, got %d", len(*prompts))
+	}
+}
+
+func TestTagVersion(t *testing.T) {
+	for tag, version := range map[string]string{"v1.2.0": "1.2.0", "V2": "2", "1.0": "1.0", "vnext": "vnext", "release-3": "release-3"} {
+		if got := tagVersion(tag); got != version {
+			t.Errorf("tagVersion(%q) = %q, expected %q", tag, got, version)
+		}
+	}
+}
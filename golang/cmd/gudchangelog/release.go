@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/changelog"
+)
+
+// runRelease moves the Unreleased section of the changelog to a new version
+// and, with --tag, commits the changelog and tags the release
+func runRelease(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("gudchangelog release", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gudchangelog release [flags] <version>\n\n"+
+			"Rename the Unreleased section of the changelog to \"[version] - date\", start a new empty\n"+
+			"Unreleased section and update the compare links at the bottom.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	var path, date string
+	var tag bool
+	fs.StringVar(&path, "file", "CHANGELOG.md", "changelog to update")
+	fs.StringVar(&date, "date", time.Now().Format("2006-01-02"), "release date, as YYYY-MM-DD")
+	fs.BoolVar(&tag, "tag", false, "commit the changelog and create an annotated tag for the release, with its section as the message")
+	if err := fs.Parse(args); err != nil {
+		return usageFailure(err)
+	}
+
+	var usage error
+	switch {
+	case fs.NArg() != 1:
+		usage = fmt.Errorf("expected one version")
+	case !isVersion(fs.Arg(0)):
+		usage = fmt.Errorf("%q is not a version such as 1.4.0 or v1.4.0", fs.Arg(0))
+	}
+	if _, err := time.Parse("2006-01-02", date); usage == nil && err != nil {
+		usage = fmt.Errorf("invalid --date %q: use YYYY-MM-DD", date)
+	}
+	if usage != nil {
+		fmt.Fprintln(fs.Output(), usage)
+		fs.Usage()
+		return usageError{usage}
+	}
+	version, tagName := tagVersion(fs.Arg(0)), releaseTag(ctx, fs.Arg(0))
+
+	if tag {
+		if _, err := gitText(ctx, "rev-parse", "--verify", "--quiet", "refs/tags/"+tagName); err == nil {
+			return fmt.Errorf("the tag %s already exists", tagName)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	file := changelog.ParseFile(string(content))
+	release, err := file.Cut(version, tagName, date)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := os.WriteFile(path, []byte(file.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Fprintf(status, "✅ Moved the Unreleased entries in %s to %s\n", path, release.Heading)
+	if !tag {
+		return nil
+	}
+
+	// Commit only the changelog, so the tag includes its release section
+	if _, err := gitText(ctx, "add", "--", path); err != nil {
+		return fmt.Errorf("failed to stage %s: %w", path, err)
+	}
+	if _, err := gitText(ctx, "commit", "--quiet", "-m", "chore(release): "+version, "--", path); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", path, err)
+	}
+	// Keep the Markdown headings, which git would strip as comments
+	message := strings.TrimSpace(release.String())
+	if _, err := gitText(ctx, "tag", "-a", "--cleanup=whitespace", "-m", message, tagName); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", tagName, err)
+	}
+	fmt.Fprintf(status, "🏷  Committed %s and tagged %s\n", path, tagName)
+	return nil
+}
+
+// releaseTag returns the tag for the release of version: version itself
+// when it has a "v", and otherwise named like the latest tag, with "v"
+// unless that tag has none
+func releaseTag(ctx context.Context, version string) string {
+	if tagVersion(version) != version {
+		return version
+	}
+	latest := tagAt(ctx, "HEAD")
+	if latest == "" {
+		latest = previousTag(ctx, "HEAD")
+	}
+	if latest != "" && tagVersion(latest) == latest {
+		return version
+	}
+	return "v" + version
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const releaseChangelog = `# Changelog
+
+## [Unreleased]
+
+### Fixed
+- Add c
+
+## [1.1.0] - 2024-05-01
+
+### Added
+- Add b
+
+[Unreleased]: https://github.com/o/r/compare/v1.1.0...HEAD
+[1.1.0]: https://github.com/o/r/compare/v1.0.0...v1.1.0
+`
+
+func TestRunRelease(t *testing.T) {
+	dir := taggedRepo(t)
+	quiet(t)
+	ctx := context.Background()
+	path := filepath.Join(dir, "CHANGELOG.md")
+	if err := os.WriteFile(path, []byte(releaseChangelog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runRelease(ctx, []string{"--tag", "1.2.0"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	today := time.Now().Format("2006-01-02")
+	content, _ := os.ReadFile(path)
+	for _, expected := range []string{
+		"## [Unreleased]\n\n## [1.2.0] - " + today + "\n\n### Fixed\n- Add c\n",
+		"[Unreleased]: https://github.com/o/r/compare/v1.2.0...HEAD\n[1.2.0]: https://github.com/o/r/compare/v1.1.0...v1.2.0\n",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, content)
+		}
+	}
+
+	// The tag is annotated, on the commit with the changelog, and keeps the
+	// Markdown headings
+	if subject := gitRun(t, dir, "log", "-1", "--format=%s", "v1.2.0"); subject != "chore(release): 1.2.0\n" {
+		t.Errorf("Expected the tag on the release commit, got %q", subject)
+	}
+	if kind := gitRun(t, dir, "cat-file", "-t", "v1.2.0"); kind != "tag\n" {
+		t.Errorf("Expected an annotated tag, got %q", kind)
+	}
+	message := gitRun(t, dir, "tag", "-l", "--format=%(contents)", "v1.2.0")
+	if !strings.HasPrefix(message, "## [1.2.0] - "+today+"\n\n### Fixed\n- Add c") {
+		t.Errorf("Unexpected tag message:\n%s", message)
+	}
+	if changes := gitRun(t, dir, "status", "--porcelain"); changes != "" {
+		t.Errorf("Expected the changelog to be committed, got:\n%s", changes)
+	}
+
+	// Nothing is left to release
+	if err := runRelease(ctx, []string{"1.3.0"}); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("Expected an error for an empty Unreleased section, got %v", err)
+	}
+
+	// An existing tag is refused before the changelog is touched
+	if err := os.WriteFile(path, []byte(releaseChangelog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runRelease(ctx, []string{"--tag", "v1.1.0"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an error for an existing tag, got %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != releaseChangelog {
+		t.Errorf("Expected the changelog unchanged, got:\n%s", content)
+	}
+}
+
+func TestRunReleaseUsage(t *testing.T) {
+	newRepo(t)
+	for _, args := range [][]string{nil, {"next"}, {"1.0.0", "2.0.0"}, {"--date", "16/10/2026", "1.0.0"}} {
+		var usage usageError
+		if err := runRelease(context.Background(), append([]string{"-file", os.DevNull}, args...)); !errors.As(err, &usage) {
+			t.Errorf("Expected a usage error for %q, got %v", args, err)
+		}
+	}
+}
+
+func TestReleaseTag(t *testing.T) {
+	dir := taggedRepo(t)
+	ctx := context.Background()
+	if tag := releaseTag(ctx, "1.2.0"); tag != "v1.2.0" {
+		t.Errorf("Expected v1.2.0, got %q", tag)
+	}
+	if tag := releaseTag(ctx, "v2.0.0"); tag != "v2.0.0" {
+		t.Errorf("Expected v2.0.0, got %q", tag)
+	}
+
+	// Follow a repository whose tags have no "v"
+	gitRun(t, dir, "tag", "1.1.1")
+	if tag := releaseTag(ctx, "1.2.0"); tag != "1.2.0" {
+		t.Errorf("Expected 1.2.0, got %q", tag)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/bedrock"
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+	"github.com/gudlyf/GudCommit/golang/pkg/convention"
+)
+
+// maxCandidates bounds --candidates so the response fits in max_tokens
+const maxCandidates = 5
+
+// conversation keeps the exchange with the model so follow-up requests
+// build on the earlier prompt and answers
+type conversation struct {
+	cfg      *config.Config
+	provider bedrock.Provider
+	history  []bedrock.Message
+}
+
+// newConversation starts a conversation with the configured provider
+func newConversation(cfg *config.Config) (*conversation, error) {
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &conversation{cfg: cfg, provider: provider}, nil
+}
+
+// newProvider returns the configured provider with its progress drawn on
+// progressOut
+func newProvider(cfg *config.Config) (bedrock.Provider, error) {
+	provider, err := bedrock.NewProvider(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	reporter, err := bedrock.NewReporterTo(cfg.Progress, progressOut)
+	if err != nil {
+		return nil, err
+	}
+	provider.SetReporter(reporter)
+	return provider, nil
+}
+
+// ask sends prompt after the earlier turns and records the exchange
+func (c *conversation) ask(ctx context.Context, prompt string) (string, error) {
+	opts := bedrock.Options{History: c.history}
+	var response string
+	var err error
+	// Print the response as it is generated so long runs show progress
+	if c.cfg.Stream {
+		response, err = bedrock.GenerateStream(ctx, c.provider, prompt, opts, func(delta string) {
+			fmt.Fprint(status, delta)
+		})
+	} else {
+		response, err = c.provider.Generate(ctx, prompt, opts)
+	}
+	if err != nil {
+		return "", err
+	}
+	c.history = append(c.history,
+		bedrock.Message{Role: "user", Content: prompt},
+		bedrock.Message{Role: "assistant", Content: response},
+	)
+	return response, nil
+}
+
+// followUpPrompt asks for new versions of the messages in the same format,
+// following guidance when it is given
+func followUpPrompt(guidance string, candidates int) string {
+	what := "a new version of the commit messages"
+	if candidates > 1 {
+		what = fmt.Sprintf("%d new alternative versions of the commit messages", candidates)
+	}
+	if guidance == "" {
+		return fmt.Sprintf("Write %s, different from the previous ones. Respond in exactly the same JSON format, with no other text.", what)
+	}
+	return fmt.Sprintf("Write %s that follow this additional guidance:\n\n%s\n\nRespond in exactly the same JSON format, with no other text.", what, guidance)
+}
+
+// candidate is one proposed set of commits and their combined message
+type candidate struct {
+	// commits is empty when the response could not be parsed and message
+	// holds the raw response
+	commits []CommitMessage
+	message string
+}
+
+// newCandidate renders commits into a candidate
+func newCandidate(commits []CommitMessage, rules convention.Rules) candidate {
+	messages := make([]string, len(commits))
+	for i, commit := range commits {
+		messages[i] = commit.Render(rules.WrapWidth)
+	}
+	return candidate{commits: commits, message: combineMessages(messages)}
+}
+
+// renderedMessages returns each commit's message, or the raw message when
+// the response could not be parsed
+func (c candidate) renderedMessages(rules convention.Rules) []string {
+	if len(c.commits) == 0 {
+		return []string{c.message}
+	}
+	messages := make([]string, len(c.commits))
+	for i, commit := range c.commits {
+		messages[i] = commit.Render(rules.WrapWidth)
+	}
+	return messages
+}
+
+// parseCandidates parses a response holding a JSON array of alternative
+// {"commits": [...]} objects, dropping invalid and duplicate alternatives.
+// A response with a single object, or one that cannot be parsed, becomes a
+// single candidate; an empty response none.
+func parseCandidates(response string, rules convention.Rules) []candidate {
+	if strings.TrimSpace(response) == "" {
+		return nil
+	}
+
+	cleaned := regexp.MustCompile("```(json)?\n?").ReplaceAllString(response, "")
+	start, end := strings.Index(cleaned, "["), strings.LastIndex(cleaned, "]")
+	if start >= 0 && end > start && strings.TrimSpace(cleaned[:start]) == "" {
+		var variants []CommitResponse
+		if err := json.Unmarshal([]byte(cleaned[start:end+1]), &variants); err == nil {
+			var out []candidate
+			seen := map[string]bool{}
+			for _, v := range variants {
+				commits, err := checkCommits(v.Commits, rules)
+				if err != nil {
+					continue
+				}
+				c := newCandidate(commits, rules)
+				if !seen[c.message] {
+					seen[c.message] = true
+					out = append(out, c)
+				}
+			}
+			if len(out) > 0 {
+				return out
+			}
+		}
+	}
+
+	commits, err := parseCommits(response, rules)
+	if err != nil {
+		// Fallback to raw response
+		return []candidate{{message: strings.TrimSpace(response)}}
+	}
+	return []candidate{newCandidate(commits, rules)}
+}
+
+// showCandidates prints numbered alternatives
+func showCandidates(candidates []candidate) {
+	fmt.Fprintln(status)
+	fmt.Fprintln(status, "📝 Candidate commit messages:")
+	for i, c := range candidates {
+		fmt.Fprintf(status, "\n%d) %s\n", i+1, bold(c.message))
+	}
+	fmt.Fprintln(status)
+}
+
+// candidateMessages returns the combined message of each candidate
+func candidateMessages(candidates []candidate) []string {
+	messages := make([]string, len(candidates))
+	for i, c := range candidates {
+		messages[i] = c.message
+	}
+	return messages
+}
+
+// isRegenerate reports whether an answer asks for new messages
+func isRegenerate(word string) bool {
+	switch word {
+	case "r", "regenerate", "g", "guidance":
+		return true
+	}
+	return false
+}
+
+// regenerate asks the model for new messages after an r or g answer and
+// returns its response. When g is given without guidance it explains and
+// returns the current response unchanged.
+func regenerate(ctx context.Context, conv *conversation, word, guidance, current string, candidates int) (string, error) {
+	if word == "r" || word == "regenerate" {
+		guidance = ""
+	} else if guidance == "" {
+		fmt.Fprintln(status, "❌ Expected guidance after g, e.g. 'g mention the new retry limit'")
+		return current, nil
+	}
+	fmt.Fprintln(status, "🤖 Regenerating commit message...")
+	completion, err := conv.ask(ctx, followUpPrompt(guidance, candidates))
+	if err != nil {
+		return "", fmt.Errorf("failed to invoke model: %w", err)
+	}
+	return completion, nil
+}
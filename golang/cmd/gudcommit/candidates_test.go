@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/bedrock"
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+)
+
+func TestParseCandidates(t *testing.T) {
+	rules := config.Defaults().Commit
+
+	tests := []struct {
+		name     string
+		response string
+		expected []string
+		parsed   bool
+	}{
+		{
+			name: "Array of alternatives",
+			response: "```json\n[" +
+				`{"commits": [{"type": "fix", "description": "handle empty diff"}]},` +
+				`{"commits": [{"type": "fix", "description": "skip empty diffs"}]},` +
+				`{"commits": [{"type": "bogus", "description": "invalid type"}]},` +
+				`{"commits": [{"type": "fix", "description": "handle empty diff"}]}` +
+				"]\n```",
+			expected: []string{"fix: handle empty diff", "fix: skip empty diffs"},
+			parsed:   true,
+		},
+		{
+			name:     "Single object",
+			response: `{"commits": [{"type": "feat", "scope": "api", "description": "add routes"}, {"type": "docs", "description": "describe routes"}]}`,
+			expected: []string{"feat(api): add routes\ndocs: describe routes"},
+			parsed:   true,
+		},
+		{
+			name:     "Object with an array inside",
+			response: `{"commits": [{"type": "fix", "description": "retry uploads", "files": ["a.go"]}]}`,
+			expected: []string{"fix: retry uploads"},
+			parsed:   true,
+		},
+		{
+			name:     "Raw text",
+			response: "  fix: something went wrong  \n",
+			expected: []string{"fix: something went wrong"},
+		},
+		{name: "Empty", response: " \n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseCandidates(tt.response, rules)
+			messages := candidateMessages(got)
+			if strings.Join(messages, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected %q, got %q", tt.expected, messages)
+			}
+			for _, c := range got {
+				if (len(c.commits) > 0) != tt.parsed {
+					t.Errorf("Expected parsed commits: %v, got %v", tt.parsed, c.commits)
+				}
+			}
+		})
+	}
+}
+
+func TestFollowUpPrompt(t *testing.T) {
+	if got := followUpPrompt("", 1); !strings.Contains(got, "a new version") || !strings.Contains(got, "different from the previous ones") {
+		t.Errorf("Unexpected prompt: %s", got)
+	}
+	got := followUpPrompt("mention the retry limit", 3)
+	if !strings.Contains(got, "3 new alternative versions") || !strings.Contains(got, "mention the retry limit") {
+		t.Errorf("Unexpected prompt: %s", got)
+	}
+}
+
+func TestConversationKeepsHistory(t *testing.T) {
+	var requests [][]bedrock.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages []bedrock.Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		requests = append(requests, payload.Messages)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "answer"}}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Provider = "openai"
+	cfg.Endpoint = server.URL
+	cfg.Progress = bedrock.ProgressSilent
+	conv, err := newConversation(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range []string{"first", "second"} {
+		if _, err := conv.ask(context.Background(), prompt); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if len(requests) != 2 || len(requests[0]) != 1 || len(requests[1]) != 3 {
+		t.Fatalf("Unexpected requests: %+v", requests)
+	}
+	if requests[1][0].Content != "first" || requests[1][1].Role != "assistant" || requests[1][1].Content != "answer" || requests[1][2].Content != "second" {
+		t.Errorf("Expected the first exchange before the follow-up, got %+v", requests[1])
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+)
+
+// hookMarker identifies hooks written by gudcommit, so install and uninstall
+// never touch someone else's hook
+const hookMarker = "# Installed by gudcommit"
+
+// hookScripts holds the script for each hook gudcommit can install; %s is
+// the quoted gudcommit path. The prepare-commit-msg hook exits 0 whatever
+// happens so the commit is never blocked, and both skip quietly when
+// gudcommit cannot be found.
+var hookScripts = map[string]string{
+	"prepare-commit-msg": `#!/bin/sh
+` + hookMarker + `: fills in a generated commit message.
+# Remove with: gudcommit install --uninstall
+GUDCOMMIT=%s
+[ -x "$GUDCOMMIT" ] || GUDCOMMIT=$(command -v gudcommit) || exit 0
+"$GUDCOMMIT" hook prepare-commit-msg "$@" || true
+`,
+	"commit-msg": `#!/bin/sh
+` + hookMarker + `: checks the message follows the commit rules.
+# Remove with: gudcommit install --hook commit-msg --uninstall
+GUDCOMMIT=%s
+[ -x "$GUDCOMMIT" ] || GUDCOMMIT=$(command -v gudcommit) || exit 0
+exec "$GUDCOMMIT" hook commit-msg "$@"
+`,
+}
+
+// skippedSources are prepare-commit-msg sources that already carry a
+// message: -m/-F, merges, squashes, and amend/-c/-C
+var skippedSources = map[string]bool{
+	"message": true,
+	"merge":   true,
+	"squash":  true,
+	"commit":  true,
+}
+
+// runInstall writes or removes a git hook
+func runInstall(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("gudcommit install", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gudcommit install [--hook <name>] [--force | --uninstall]\n\n"+
+			"Install a prepare-commit-msg hook that fills in a generated message when you run git commit,\n"+
+			"or a commit-msg hook that rejects messages that do not follow the commit rules.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	name := fs.String("hook", "prepare-commit-msg", "hook to install: prepare-commit-msg or commit-msg")
+	force := fs.Bool("force", false, "replace an existing hook that was not installed by gudcommit")
+	uninstall := fs.Bool("uninstall", false, "remove the hook installed by gudcommit")
+	if err := fs.Parse(args); err != nil {
+		return usageFailure(err)
+	}
+	script, ok := hookScripts[*name]
+	var err error
+	switch {
+	case fs.NArg() > 0:
+		err = fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	case !ok:
+		err = fmt.Errorf("unknown hook %q (expected prepare-commit-msg or commit-msg)", *name)
+	}
+	if err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return usageError{err}
+	}
+
+	dir, err := hooksDir(ctx)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, *name)
+
+	if *uninstall {
+		removed, err := removeHook(path)
+		if err != nil {
+			return err
+		}
+		if removed {
+			fmt.Fprintf(status, "✅ Removed %s\n", path)
+		} else {
+			fmt.Fprintf(status, ">> No gudcommit hook at %s\n", path)
+		}
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the gudcommit executable: %w", err)
+	}
+	if err := installHook(path, fmt.Sprintf(script, shellQuote(exe)), *force); err != nil {
+		return err
+	}
+	fmt.Fprintf(status, "✅ Installed %s\n", path)
+	return nil
+}
+
+// hooksDir returns the repository's hooks directory, honouring
+// core.hooksPath
+func hooksDir(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository")
+	}
+	return filepath.Abs(strings.TrimSpace(string(out)))
+}
+
+// installHook writes script to path. An existing hook is only replaced if
+// gudcommit wrote it or force is set.
+func installHook(path, script string, force bool) error {
+	if data, err := os.ReadFile(path); err == nil && !force && !strings.Contains(string(data), hookMarker) {
+		return fmt.Errorf("%s already exists and was not installed by gudcommit; use --force to replace it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		return fmt.Errorf("failed to write hook: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0o755)
+}
+
+// removeHook deletes the hook at path if gudcommit installed it
+func removeHook(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !strings.Contains(string(data), hookMarker) {
+		return false, fmt.Errorf("%s was not installed by gudcommit; remove it by hand", path)
+	}
+	return true, os.Remove(path)
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// errHookUsage is returned when the hook command is called incorrectly
+var errHookUsage = errors.New("usage: gudcommit hook prepare-commit-msg <message-file> [<source> [<sha>]]\n" +
+	"       gudcommit hook commit-msg <message-file>")
+
+// runHook is called by the installed hooks as "gudcommit hook <name> <args>"
+func runHook(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errHookUsage
+	}
+	// Hook output is for people; keep stdout free for git
+	status = os.Stderr
+	progressOut = os.Stderr
+	colorize = false
+
+	switch args[0] {
+	case "prepare-commit-msg":
+		if len(args) < 2 {
+			return errHookUsage
+		}
+		source := ""
+		if len(args) > 2 {
+			source = args[2]
+		}
+		// Never block the commit: report the problem and leave the file as is
+		if err := prepareCommitMsg(ctx, args[1], source); err != nil {
+			fmt.Fprintf(status, "⚠ :: gudcommit could not generate a commit message: %v\n", err)
+		}
+		return nil
+	case "commit-msg":
+		if len(args) < 2 {
+			return errHookUsage
+		}
+		return commitMsg(ctx, args[1])
+	case "sequence-editor", "reword-editor":
+		// Stand-ins for the editors during gudcommit reword's rebase
+		if len(args) < 2 {
+			return errHookUsage
+		}
+		if args[0] == "sequence-editor" {
+			return sequenceEditor(ctx, args[1])
+		}
+		return rewordEditor(ctx, args[1])
+	default:
+		return fmt.Errorf("unknown hook %q; %w", args[0], errHookUsage)
+	}
+}
+
+// prepareCommitMsg writes a generated message for the staged changes at the
+// top of the message file, above git's comments. It does nothing when git
+// already has a message for the commit.
+func prepareCommitMsg(ctx context.Context, file, source string) error {
+	if skippedSources[source] {
+		return nil
+	}
+
+	repoPath := repoRoot(ctx)
+	cfg, err := config.Load(repoPath)
+	if err != nil {
+		return err
+	}
+
+	// git points GIT_INDEX_FILE at the index being committed, which covers
+	// commit --all and partial commits too
+	diffOutput, err := getGitDiff(ctx, []string{"diff", "--staged"})
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(diffOutput) == "" {
+		return nil
+	}
+	diffOutput = strings.ReplaceAll(diffOutput, "\\", "\\\\")
+
+	fmt.Fprintln(status, "🤖 Generating commit message...")
+	_, completion, err := invokeModel(ctx, cfg, diffOutput, repoPath, nil, 1)
+	if err != nil {
+		return err
+	}
+	commits, err := parseCommits(completion, cfg.Commit)
+	if err != nil {
+		return err
+	}
+	messages := make([]string, len(commits))
+	for i, commit := range commits {
+		messages[i] = commit.Render(cfg.Commit.WrapWidth)
+	}
+
+	existing, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read commit message file: %w", err)
+	}
+	content := combineMessages(messages) + "\n"
+	if len(existing) > 0 && existing[0] != '\n' {
+		content += "\n"
+	}
+	return os.WriteFile(file, append([]byte(content), existing...), 0o644)
+}
+
+// commitMsg rejects the commit when the message in file breaks the commit
+// rules, suggesting a rewrite when commit.lint.suggest is set
+func commitMsg(ctx context.Context, file string) error {
+	cfg, err := config.Load(repoRoot(ctx))
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read commit message file: %w", err)
+	}
+	if n := lintMessage(ctx, cfg, "", string(data), cfg.Commit.Lint.Suggest); n > 0 {
+		return fmt.Errorf("commit message has %d problem(s); your message is saved in %s", n, file)
+	}
+	return nil
+}
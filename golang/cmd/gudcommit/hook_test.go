@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRepo creates a repository with one staged file and makes it the
+// working directory for the test
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig-none"))
+	gitRun(t, dir, "init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, dir, "add", "a.txt")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// fakeModel serves OpenAI-style chat completions with content, and points
+// the repository's configuration at it
+func fakeModel(t *testing.T, dir string, status int, content string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := `{"provider": "openai", "endpoint": "` + server.URL + `", "progress": "silent", "retry": {"max_attempts": 1}}`
+	if err := os.WriteFile(filepath.Join(dir, ".gudcommit.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hooks", "prepare-commit-msg")
+
+	script := fmt.Sprintf(hookScripts["prepare-commit-msg"], shellQuote("/opt/it's/gudcommit"))
+	if err := installHook(path, script, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `GUDCOMMIT='/opt/it'\''s/gudcommit'`) || !strings.Contains(string(data), "|| true") {
+		t.Errorf("Unexpected hook script:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode()&0o111 == 0 {
+		t.Error("Expected the hook to be executable")
+	}
+
+	// Reinstalling over our own hook is fine; someone else's needs --force
+	if err := installHook(path, script, false); err != nil {
+		t.Errorf("Unexpected error reinstalling: %v", err)
+	}
+	os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0o755)
+	if err := installHook(path, script, false); err == nil {
+		t.Error("Expected error replacing a foreign hook")
+	}
+	if _, err := removeHook(path); err == nil {
+		t.Error("Expected error removing a foreign hook")
+	}
+	if err := installHook(path, script, true); err != nil {
+		t.Errorf("Unexpected error with force: %v", err)
+	}
+
+	if removed, err := removeHook(path); !removed || err != nil {
+		t.Errorf("Expected hook to be removed, got %v %v", removed, err)
+	}
+	if removed, err := removeHook(path); removed || err != nil {
+		t.Errorf("Expected nothing to remove, got %v %v", removed, err)
+	}
+}
+
+func TestPrepareCommitMsgHook(t *testing.T) {
+	dir := newRepo(t)
+	fakeModel(t, dir, http.StatusOK, `{"commits": [{"type": "feat", "scope": "a", "description": "add a"}]}`)
+	msgFile := filepath.Join(dir, ".git", "COMMIT_EDITMSG")
+	comments := "\n# Please enter the commit message for your changes.\n"
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"plain commit", []string{msgFile}, "feat(a): add a\n" + comments},
+		{"template", []string{msgFile, "template"}, "feat(a): add a\n" + comments},
+		{"message", []string{msgFile, "message"}, comments},
+		{"merge", []string{msgFile, "merge"}, comments},
+		{"squash", []string{msgFile, "squash"}, comments},
+		{"amend", []string{msgFile, "commit", "HEAD"}, comments},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.WriteFile(msgFile, []byte(comments), 0o644)
+			if err := runHook(context.Background(), append([]string{"prepare-commit-msg"}, tt.args...)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			data, _ := os.ReadFile(msgFile)
+			if string(data) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestPrepareCommitMsgHookProgress(t *testing.T) {
+	dir := newRepo(t)
+	fakeModel(t, dir, http.StatusOK, `{"commits": [{"type": "feat", "scope": "a", "description": "add a"}]}`)
+	// Draw the spinner whether or not the output is a terminal
+	cfg, _ := os.ReadFile(filepath.Join(dir, ".gudcommit.json"))
+	cfg = []byte(strings.Replace(string(cfg), `"silent"`, `"spinner"`, 1))
+	if err := os.WriteFile(filepath.Join(dir, ".gudcommit.json"), cfg, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	msgFile := filepath.Join(dir, ".git", "COMMIT_EDITMSG")
+	os.WriteFile(msgFile, []byte("# comments\n"), 0o644)
+
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	origStdout, origStderr, origStatus, origProgress := os.Stdout, os.Stderr, status, progressOut
+	os.Stdout, os.Stderr = stdout, stderr
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr, status, progressOut = origStdout, origStderr, origStatus, origProgress
+	})
+
+	if err := runHook(context.Background(), []string{"prepare-commit-msg", msgFile}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out, _ := os.ReadFile(stdout.Name()); len(out) > 0 {
+		t.Errorf("Expected nothing on stdout, got %q", out)
+	}
+	if out, _ := os.ReadFile(stderr.Name()); !strings.Contains(string(out), "Response received from OpenAI") {
+		t.Errorf("Expected the progress on stderr, got %q", out)
+	}
+}
+
+func TestPrepareCommitMsgHookNeverBlocks(t *testing.T) {
+	dir := newRepo(t)
+	fakeModel(t, dir, http.StatusInternalServerError, "")
+	msgFile := filepath.Join(dir, ".git", "COMMIT_EDITMSG")
+	os.WriteFile(msgFile, []byte("# comments\n"), 0o644)
+
+	if err := runHook(context.Background(), []string{"prepare-commit-msg", msgFile}); err != nil {
+		t.Fatalf("Expected the hook to swallow model errors, got %v", err)
+	}
+	if data, _ := os.ReadFile(msgFile); string(data) != "# comments\n" {
+		t.Errorf("Expected the message file to be untouched, got %q", data)
+	}
+
+	if err := runHook(context.Background(), []string{"post-commit"}); err == nil {
+		t.Error("Expected error for an unknown hook")
+	}
+}
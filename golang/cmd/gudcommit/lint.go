@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/bedrock"
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+	"github.com/gudlyf/GudCommit/golang/pkg/convention"
+	"github.com/gudlyf/GudCommit/golang/pkg/redact"
+)
+
+// runLint checks commit messages against the configured commit rules
+func runLint(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("gudcommit lint", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gudcommit lint [flags] [<message-file> | -]\n       gudcommit lint [flags] --range <revision-range>\n\n"+
+			"Check commit messages against Conventional Commits and the configured commit rules.\n"+
+			"The message is read from the file, or from stdin when none is given.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	suggest := fs.Bool("suggest", false, "ask the model for a rewrite of messages that fail (also commit.lint.suggest)")
+	revRange := fs.String("range", "", "check every non-merge commit in a revision range, e.g. origin/main..HEAD")
+	if err := fs.Parse(args); err != nil {
+		return usageFailure(err)
+	}
+	if fs.NArg() > 1 || (fs.NArg() > 0 && *revRange != "") {
+		err := fmt.Errorf("expected one message file or --range")
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return usageError{err}
+	}
+
+	repoPath := repoRoot(ctx)
+	cfg, err := config.Load(repoPath)
+	if err != nil {
+		return err
+	}
+	*suggest = *suggest || cfg.Commit.Lint.Suggest
+
+	if *revRange != "" {
+		return lintRange(ctx, cfg, *revRange, *suggest)
+	}
+
+	var data []byte
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read commit message: %w", err)
+	}
+	if n := lintMessage(ctx, cfg, "", string(data), *suggest); n > 0 {
+		return fmt.Errorf("commit message has %d problem(s)", n)
+	}
+	fmt.Fprintln(status, "✅ Commit message follows the commit rules")
+	return nil
+}
+
+// lintRange checks the message of every non-merge commit in revRange
+func lintRange(ctx context.Context, cfg *config.Config, revRange string, suggest bool) error {
+	out, err := exec.CommandContext(ctx, "git", "rev-list", "--no-merges", "--reverse", revRange, "--").Output()
+	if err != nil {
+		return fmt.Errorf("failed to list commits in %s: %w", revRange, err)
+	}
+
+	failed := 0
+	revs := strings.Fields(string(out))
+	for _, rev := range revs {
+		message, err := exec.CommandContext(ctx, "git", "log", "-1", "--format=%B", rev).Output()
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %w", rev, err)
+		}
+		if lintMessage(ctx, cfg, rev[:min(len(rev), 12)], string(message), suggest) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d commit(s) do not follow the commit rules", failed, len(revs))
+	}
+	fmt.Fprintf(status, "✅ %d commit(s) follow the commit rules\n", len(revs))
+	return nil
+}
+
+// lintMessage prints each violation in message, prefixed by label when
+// set, and a rewrite from the model when suggest is set. It returns the
+// number of violations.
+func lintMessage(ctx context.Context, cfg *config.Config, label, message string, suggest bool) int {
+	violations := cfg.Commit.LintMessage(message)
+	if len(violations) == 0 {
+		return 0
+	}
+
+	prefix := ""
+	if label != "" {
+		prefix = label + " "
+	}
+	header, _, _ := strings.Cut(convention.CleanMessage(message), "\n")
+	fmt.Fprintf(status, "❌ %s%s\n", prefix, bold(header))
+	for _, v := range violations {
+		fmt.Fprintf(status, "   %s\n", v)
+	}
+
+	if suggest {
+		rewrite, err := suggestRewrite(ctx, cfg, message, violations)
+		if err != nil {
+			fmt.Fprintf(status, "⚠ :: Could not suggest a rewrite: %v\n", err)
+		} else {
+			fmt.Fprintln(status, "💡 Suggested rewrite:")
+			fmt.Fprintln(status, bold(rewrite))
+		}
+	}
+	return len(violations)
+}
+
+// suggestRewrite asks the model to fix the violations in message, with
+// secrets in it redacted first
+func suggestRewrite(ctx context.Context, cfg *config.Config, message string, violations []convention.Violation) (string, error) {
+	message, err := redactText(cfg, convention.CleanMessage(message))
+	if err != nil {
+		return "", err
+	}
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	problems := make([]string, len(violations))
+	for i, v := range violations {
+		problems[i] = "- " + v.String()
+	}
+	prompt := fmt.Sprintf(`Rewrite the following commit message so that it follows the rules below, keeping its meaning. Do not invent details that are not in the original.
+
+<commit_message>
+%s
+</commit_message>
+
+It currently has these problems:
+%s
+
+Respond with JSON matching this exact schema, with exactly one commit:
+
+%s
+
+Rules:
+%s
+- Do not include any explanatory text outside the JSON`, message, strings.Join(problems, "\n"), cfg.Commit.Schema(), cfg.Commit.PromptRules())
+
+	completion, err := provider.Generate(ctx, prompt, bedrock.Options{})
+	if err != nil {
+		return "", err
+	}
+	commits, err := parseCommits(completion, cfg.Commit)
+	if err != nil {
+		return "", err
+	}
+	return commits[0].Render(cfg.Commit.WrapWidth), nil
+}
+
+// redactText redacts secrets in text that goes to the model besides the
+// diff, such as an existing commit message
+func redactText(cfg *config.Config, text string) (string, error) {
+	text, findings, err := redact.Apply(cfg.Redact, text)
+	if err != nil {
+		return "", err
+	}
+	if len(findings) > 0 {
+		fmt.Fprintf(status, "🔒 :: Redacted %d possible secret(s) in the commit message before sending\n", len(findings))
+	}
+	return text, nil
+}
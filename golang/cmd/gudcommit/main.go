@@ -1,45 +1,93 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
 	"os/exec"
+	"os/signal"
 	"regexp"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gudlyf/GudCommit/golang/pkg/bedrock"
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+	"github.com/gudlyf/GudCommit/golang/pkg/convention"
+	"github.com/gudlyf/GudCommit/golang/pkg/diff"
+	"github.com/gudlyf/GudCommit/golang/pkg/redact"
+	"github.com/gudlyf/GudCommit/golang/pkg/split"
+	"github.com/gudlyf/GudCommit/golang/pkg/term"
 )
 
 // CommitMessage represents a structured commit message
-type CommitMessage struct {
-	Type        string `json:"type"`
-	Scope       string `json:"scope"`
-	Description string `json:"description"`
-}
+type CommitMessage = convention.Message
 
 // CommitResponse represents the JSON response from Bedrock
 type CommitResponse struct {
 	Commits []CommitMessage `json:"commits"`
 }
 
-// checkStagedChanges checks if there are staged changes
-func checkStagedChanges() error {
-	cmd := exec.Command("git", "diff", "--staged", "--quiet")
+// diffArgs returns the git diff arguments for the changes being committed:
+// the staged changes, tracked changes in the work tree with --all, and, with
+// --amend, those plus the changes already in HEAD
+func diffArgs(ctx context.Context, opts *options) []string {
+	args := []string{"diff"}
+	if !opts.all {
+		args = append(args, "--staged")
+	}
+	switch {
+	case opts.amend:
+		args = append(args, revOrEmptyTree(ctx, "HEAD^"))
+	case opts.all:
+		args = append(args, revOrEmptyTree(ctx, "HEAD"))
+	}
+	return args
+}
+
+// revOrEmptyTree returns rev if it exists and the empty tree otherwise, so
+// diffs work before the first commit and when amending a root commit
+func revOrEmptyTree(ctx context.Context, rev string) string {
+	if exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", rev).Run() != nil {
+		return diff.EmptyTree
+	}
+	return rev
+}
+
+// checkStagedChanges checks if there are changes to commit
+func checkStagedChanges(ctx context.Context, args []string) error {
+	cmd := exec.CommandContext(ctx, "git", append(args, "--quiet")...)
 	err := cmd.Run()
 	if err == nil {
-		// Exit code 0 means no staged changes (quiet = no differences)
+		// Exit code 0 means no changes (quiet = no differences)
+		if !slices.Contains(args, "--staged") {
+			return fmt.Errorf("no changes to tracked files found")
+		}
 		return fmt.Errorf("no staged changes found. Please stage your changes first with 'git add'")
 	}
-	// Exit code 1 means there ARE staged changes (not quiet = differences exist)
-	return nil
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	// Exit code 1 means there ARE changes (not quiet = differences exist)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return nil
+	}
+	return fmt.Errorf("failed to check for changes: %w", err)
 }
 
-// getGitDiff retrieves the staged changes from git using git command
-func getGitDiff() (string, error) {
-	cmd := exec.Command("git", "diff", "--staged")
+// getGitDiff retrieves the changes to commit using git command
+func getGitDiff(ctx context.Context, args []string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get git diff: %w", err)
@@ -47,9 +95,22 @@ func getGitDiff() (string, error) {
 	return string(output), nil
 }
 
+// commitArgs returns the extra git commit arguments for --all and --amend
+func commitArgs(opts *options) []string {
+	var args []string
+	if opts.all {
+		args = append(args, "--all")
+	}
+	if opts.amend {
+		args = append(args, "--amend")
+	}
+	return args
+}
+
 // executeGitCommit executes the git commit with the given message
-func executeGitCommit(message string) error {
-	cmd := exec.Command("git", "commit", "-m", message)
+func executeGitCommit(ctx context.Context, message string, extra ...string) error {
+	args := append([]string{"commit", "-m", message}, extra...)
+	cmd := exec.CommandContext(ctx, "git", args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("git commit failed: %s", string(output))
@@ -58,74 +119,82 @@ func executeGitCommit(message string) error {
 }
 
 // executeGitCommitEdit executes git commit with editor
-func executeGitCommitEdit(message string) error {
-	cmd := exec.Command("git", "commit", "-e", "-m", message)
+func executeGitCommitEdit(ctx context.Context, message string, extra ...string) error {
+	args := append([]string{"commit", "-e", "-m", message}, extra...)
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
-// invokeBedrockModel invokes Bedrock using the shared package
-func invokeBedrockModel(prompt, repoPath string) (string, error) {
-	client, err := bedrock.NewClient()
+// invokeModel starts a conversation with the configured model provider and
+// sends it the prompt template. guidance holds extra rules, one per line,
+// such as the --type and --scope hints, and candidates is the number of
+// alternative versions to ask for.
+func invokeModel(ctx context.Context, cfg *config.Config, prompt, repoPath string, guidance []string, candidates int) (*conversation, string, error) {
+	conv, err := newConversation(cfg)
 	if err != nil {
-		return "", err
+		return nil, "", err
+	}
+
+	// Filter, redact and, if it is too large for one request, summarise the diff
+	fitted, err := prepareDiff(ctx, cfg, prompt, repoPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rules := cfg.Commit.PromptRules()
+	for _, line := range guidance {
+		rules += "\n- " + line
+	}
+	respond := "Respond with JSON matching this exact schema:"
+	if candidates > 1 {
+		respond = fmt.Sprintf("Write %d alternative versions that differ in wording and emphasis. "+
+			"Respond with a JSON array of %d objects, each matching this exact schema:", candidates, candidates)
 	}
 
 	// Create the prompt with the same template as before
-	fullPrompt := fmt.Sprintf(`Analyze the following git diff and generate commit messages in the specified JSON format.
+	fullPrompt := fmt.Sprintf(`Analyze the following %s and generate commit messages in the specified JSON format.
 
 Repository root: %s
 
-<git_diff>%s</git_diff>
-
-Respond with JSON matching this exact schema:
-
-{
-  "type": "object",
-  "properties": {
-    "commits": {
-      "type": "array",
-      "items": {
-        "type": "object",
-        "properties": {
-          "type": {
-            "type": "string",
-            "enum": ["feat", "fix", "build", "chore", "ci", "docs", "style", "refactor", "perf", "test"]
-          },
-          "scope": {
-            "type": "string",
-            "description": "The full file path or component being changed (e.g., 'src/utils.js', 'terraform/module/main.tf', 'golang/cmd/gudcommit/main.go')"
-          },
-          "description": {
-            "type": "string",
-            "description": "Brief description of the change and why it was done"
-          }
-        },
-        "required": ["type", "scope", "description"],
-        "additionalProperties": false
-      }
-    }
-  },
-  "required": ["commits"],
-  "additionalProperties": false
-}
+%s
+
+%s
+
+%s
 
 Rules:
-- Use conventional commit format: type(scope): description
-- Types: feat, fix, build, chore, ci, docs, style, refactor, perf, test
-- Scope should be the full file path (e.g., 'golang/cmd/gudcommit/main.go', 'terraform/module/bedrock.tf')
+%s
 - Be concise and clear
 - Focus on WHAT changed and WHY
 - Do not include any explanatory text outside the JSON
-- Each changed file should have its own commit entry`, repoPath, prompt)
+- Each changed file should have its own commit entry`, fitted.Subject(), repoPath, fitted.Block(), respond, cfg.Commit.Schema(), rules)
+
+	completion, err := conv.ask(ctx, fullPrompt)
+	return conv, completion, err
+}
+
+// prepareDiff filters, redacts and budgets the diff with diff.Prepare,
+// summarising a diff that is too large with a provider of its own
+func prepareDiff(ctx context.Context, cfg *config.Config, diffText, repoPath string) (diff.Result, error) {
+	// Summaries run in parallel, so keep their progress off the spinner
+	summarizer, err := bedrock.NewProvider(cfg.Config)
+	if err != nil {
+		return diff.Result{}, err
+	}
+	summarizer.SetReporter(bedrock.SilentReporter{})
 
-	return client.InvokeModel(fullPrompt, repoPath)
+	generate := func(ctx context.Context, prompt string) (string, error) {
+		return summarizer.Generate(ctx, prompt, bedrock.Options{})
+	}
+	return diff.Prepare(ctx, cfg.Diff, cfg.Redact, diffText, repoPath, generate, status)
 }
 
-// parseCommitResponse parses the response from Bedrock and returns formatted commit messages
-func parseCommitResponse(response string) ([]string, error) {
+// parseCommits parses the response from Bedrock into commit messages that
+// follow rules
+func parseCommits(response string, rules convention.Rules) ([]CommitMessage, error) {
 	// Clean the response - remove any markdown formatting or extra text
 	cleanedResponse := regexp.MustCompile("```json\n?").ReplaceAllString(response, "")
 	cleanedResponse = regexp.MustCompile("```\n?").ReplaceAllString(cleanedResponse, "")
@@ -136,53 +205,52 @@ func parseCommitResponse(response string) ([]string, error) {
 	var commitResp CommitResponse
 	if err := json.Unmarshal([]byte(cleanedResponse), &commitResp); err != nil {
 		// Fallback: try to extract conventional commit format from the response
-		fallbackCommits := extractFallbackCommits(response)
+		var fallbackCommits []CommitMessage
+		for _, line := range extractFallbackCommits(response, rules) {
+			if commit, ok := rules.ParseHeader(line); ok {
+				fallbackCommits = append(fallbackCommits, commit)
+			}
+		}
 		if len(fallbackCommits) > 0 {
 			return fallbackCommits, nil
 		}
 		return nil, fmt.Errorf("failed to parse commit response: %w", err)
 	}
 
-	if len(commitResp.Commits) == 0 {
-		return nil, fmt.Errorf("no commits found in response")
-	}
+	return checkCommits(commitResp.Commits, rules)
+}
 
-	var messages []string
-	validTypes := map[string]bool{
-		"feat": true, "fix": true, "build": true, "chore": true, "ci": true,
-		"docs": true, "style": true, "refactor": true, "perf": true, "test": true,
+// checkCommits validates parsed commits against rules and normalizes their
+// scopes
+func checkCommits(parsed []CommitMessage, rules convention.Rules) ([]CommitMessage, error) {
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no commits found in response")
 	}
 
-	for _, commit := range commitResp.Commits {
+	var commits []CommitMessage
+	for _, commit := range parsed {
 		if commit.Type == "" || commit.Description == "" {
 			return nil, fmt.Errorf("invalid commit format: missing required fields")
 		}
 
-		if !validTypes[commit.Type] {
-			return nil, fmt.Errorf("invalid commit type: %s", commit.Type)
-		}
-
-		// Format the commit message with proper scope handling
-		if commit.Scope == "" || commit.Scope == "null" {
-			// No scope - use simple format
-			messages = append(messages, fmt.Sprintf("%s: %s", commit.Type, commit.Description))
-		} else {
-			// Has scope - use conventional format
-			messages = append(messages, fmt.Sprintf("%s(%s): %s", commit.Type, commit.Scope, commit.Description))
+		commit.Scope = rules.NormalizeScope(commit.Scope)
+		if err := rules.Check(commit.Type, commit.Scope); err != nil {
+			return nil, err
 		}
+		commits = append(commits, commit)
 	}
 
-	return messages, nil
+	return commits, nil
 }
 
-// extractFallbackCommits extracts conventional commit messages from unstructured response
-func extractFallbackCommits(response string) []string {
-	conventionalCommitRegex := regexp.MustCompile(`^(feat|fix|build|chore|ci|docs|style|refactor|perf|test)\([^)]+\): .+$`)
+// extractFallbackCommits extracts conventional commit messages that follow
+// rules from unstructured response
+func extractFallbackCommits(response string, rules convention.Rules) []string {
 	lines := strings.Split(response, "\n")
 	var commits []string
 
 	for _, line := range lines {
-		if conventionalCommitRegex.MatchString(strings.TrimSpace(line)) {
+		if _, ok := rules.ParseHeader(line); ok {
 			commits = append(commits, strings.TrimSpace(line))
 		}
 	}
@@ -190,125 +258,588 @@ func extractFallbackCommits(response string) []string {
 	return commits
 }
 
-// promptUser prompts the user for confirmation
-func promptUser(message string) (string, error) {
-	fmt.Print("Proceed with the commit? (y/n or e to Edit): ")
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+// repoRoot returns the top-level directory of the repository, or "." when
+// it cannot be determined
+func repoRoot(ctx context.Context) string {
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "."
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// promptUser asks question and returns the answer
+func promptUser(ctx context.Context, question string) (string, error) {
+	fmt.Fprint(status, question)
+	return term.ReadLine(ctx)
+}
+
+// splitAnswer returns the lower-cased first word of an answer and the rest
+// of it as typed
+func splitAnswer(response string) (string, string) {
+	word, rest, _ := strings.Cut(strings.TrimSpace(response), " ")
+	return strings.ToLower(word), strings.TrimSpace(rest)
+}
+
+// splitCommits shows which staged files each proposed commit covers, lets
+// the user merge proposals or move files between them, and then creates the
+// commits in sequence. With --dry-run it only shows the plan, and with --yes
+// it commits the plan as proposed. It reports whether commits were created.
+func splitCommits(ctx context.Context, repoPath string, commits []CommitMessage, rules convention.Rules, opts *options) (bool, error) {
+	staged, err := split.StagedFiles(ctx, repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to list staged files: %w", err)
+	}
+
+	messages := make([]string, len(commits))
+	claims := make([][]string, len(commits))
+	for i, commit := range commits {
+		messages[i] = commit.Render(rules.WrapWidth)
+		claims[i] = commit.Files
+	}
+	groups := split.Plan(messages, claims, staged)
+
+	for {
+		fmt.Fprintln(status)
+		fmt.Fprintln(status, "📝 Proposed commits:")
+		var files []string
+		for i, g := range groups {
+			fmt.Fprintf(status, "%s\n", bold(fmt.Sprintf("%d. %s", i+1, g.Message)))
+			for _, f := range g.Files {
+				files = append(files, f)
+				fmt.Fprintf(status, "     [%d] %s\n", len(files), f)
+			}
+		}
+		fmt.Fprintln(status)
+
+		if opts.dryRun {
+			return false, nil
+		}
+		response := "y"
+		if !opts.yes {
+			response, err = promptUser(ctx, "Create these commits? (y/n, m <commit> <commit> to Merge, r <file> <commit> to Reassign): ")
+			if err != nil {
+				return false, fmt.Errorf("failed to read user input: %w", err)
+			}
+		}
+
+		fields := strings.Fields(strings.ToLower(response))
+		if len(fields) == 0 {
+			fields = []string{"n"}
+		}
+		args := make([]int, 0, 2)
+		for _, field := range fields[1:] {
+			n, convErr := strconv.Atoi(field)
+			if convErr != nil {
+				n = 0
+			}
+			args = append(args, n-1)
+		}
+
+		switch {
+		case fields[0] == "y" || fields[0] == "yes":
+			if err := split.Commit(ctx, repoPath, groups); err != nil {
+				return false, fmt.Errorf("failed to create commits, staged changes were restored: %w", err)
+			}
+			fmt.Fprintf(status, "✅ Created %d commits!\n", len(groups))
+			return true, nil
+		case (fields[0] == "m" || fields[0] == "merge") && len(args) == 2:
+			if groups, err = split.Merge(groups, args[0], args[1]); err != nil {
+				fmt.Fprintf(status, "❌ %v\n", err)
+			}
+		case (fields[0] == "r" || fields[0] == "reassign") && len(args) == 2:
+			if args[0] < 0 || args[0] >= len(files) {
+				fmt.Fprintf(status, "❌ No file %d\n", args[0]+1)
+				continue
+			}
+			if groups, err = split.Move(groups, files[args[0]], args[1]); err != nil {
+				fmt.Fprintf(status, "❌ %v\n", err)
+			}
+		case fields[0] == "m" || fields[0] == "merge" || fields[0] == "r" || fields[0] == "reassign":
+			fmt.Fprintln(status, "❌ Expected two numbers, e.g. 'm 1 2' or 'r 3 1'")
+		default:
+			fmt.Fprintln(status, "Commit canceled.")
+			return false, nil
+		}
+	}
+}
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// status receives progress and messages meant for people; --json moves it to
+// stderr so stdout holds only the result
+var status io.Writer = os.Stdout
+
+// progressOut is where the provider's spinner is drawn
+var progressOut = os.Stdout
+
+// colorize enables ANSI styling of messages
+var colorize = true
+
+// bold styles s when colour is enabled
+func bold(s string) string {
+	if !colorize {
+		return s
+	}
+	return "\033[1m" + s + "\033[0m"
+}
+
+// versionString returns the build version, falling back to the module
+// version when installed with go install
+func versionString() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// options holds the command-line flags
+type options struct {
+	showConfig bool
+	split      bool
+	yes        bool
+	dryRun     bool
+	all        bool
+	amend      bool
+	noColor    bool
+	json       bool
+	version    bool
+	model      string
+	region     string
+	timeout    time.Duration
+	candidates int
+	typ        string
+	scope      string
+}
+
+// usageError is a command-line error that has already been reported along
+// with the usage text
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+// usageFailure wraps a flag parsing error; flag.ErrHelp is passed through
+func usageFailure(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return usageError{err}
+}
+
+// parseFlags parses the command-line arguments. It returns flag.ErrHelp
+// after printing usage for -h and --help.
+func parseFlags(args []string) (*options, error) {
+	opts := &options{}
+	fs := flag.NewFlagSet("gudcommit", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gudcommit [flags]\n       gudcommit install [--hook <name>] [--force | --uninstall]\n"+
+			"       gudcommit lint [--suggest] [<message-file> | --range <revision-range>]\n"+
+			"       gudcommit reword [--yes] [--dry-run] <commit> | <base>..[HEAD]\n\n"+
+			"Generate a commit message for the staged changes and commit them.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&opts.showConfig, "show-config", false, "print the effective configuration and where each value came from")
+	fs.BoolVar(&opts.split, "split", false, "create one commit per proposed message instead of combining them")
+	fs.BoolVar(&opts.yes, "yes", false, "commit without prompting")
+	fs.BoolVar(&opts.yes, "y", false, "shorthand for --yes")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "print the commit message without committing")
+	fs.BoolVar(&opts.all, "all", false, "include unstaged changes to tracked files, like git commit --all")
+	fs.BoolVar(&opts.amend, "amend", false, "write a new message for HEAD, including any staged changes, and amend it")
+	fs.BoolVar(&opts.noColor, "no-color", false, "disable coloured output (also set by NO_COLOR)")
+	fs.BoolVar(&opts.json, "json", false, "print the result as JSON on stdout; implies --dry-run unless --yes is given")
+	fs.BoolVar(&opts.version, "version", false, "print the version and exit")
+	fs.StringVar(&opts.model, "model", "", "model ID to use, overriding the configuration")
+	fs.StringVar(&opts.region, "region", "", "AWS region to use, overriding the configuration")
+	fs.DurationVar(&opts.timeout, "timeout", 0, "HTTP timeout for model requests, e.g. 90s or 2m")
+	fs.StringVar(&opts.typ, "type", "", "commit type the message should use, e.g. fix")
+	fs.StringVar(&opts.scope, "scope", "", "scope the message should use")
+	fs.IntVar(&opts.candidates, "candidates", 1, fmt.Sprintf("number of alternative messages to choose from, up to %d", maxCandidates))
+	if err := fs.Parse(args); err != nil {
+		return nil, usageFailure(err)
+	}
+
+	var err error
+	switch {
+	case fs.NArg() > 0:
+		err = fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	case opts.timeout < 0:
+		err = fmt.Errorf("--timeout must not be negative")
+	case opts.candidates < 1 || opts.candidates > maxCandidates:
+		err = fmt.Errorf("--candidates must be between 1 and %d", maxCandidates)
+	case opts.split && (opts.all || opts.amend):
+		err = fmt.Errorf("--split cannot be combined with --all or --amend; stage the changes to split instead")
+	}
 	if err != nil {
-		return "", err
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return nil, usageError{err}
 	}
-	return strings.TrimSpace(strings.ToLower(response)), nil
+
+	if opts.json && !opts.yes {
+		opts.dryRun = true
+	}
+	return opts, nil
+}
+
+// applyFlags layers the flags that override configuration on top of cfg,
+// so they take precedence and show up in --show-config
+func applyFlags(cfg *config.Config, opts *options) error {
+	if opts.model != "" {
+		if err := cfg.Apply("flag --model", map[string]interface{}{"model_id": opts.model}); err != nil {
+			return err
+		}
+	}
+	if opts.region != "" {
+		if err := cfg.Apply("flag --region", map[string]interface{}{"region": opts.region}); err != nil {
+			return err
+		}
+	}
+	if opts.timeout > 0 {
+		seconds := int(math.Ceil(opts.timeout.Seconds()))
+		if err := cfg.Apply("flag --timeout", map[string]interface{}{"timeout_seconds": seconds}); err != nil {
+			return err
+		}
+	}
+	if opts.json && (cfg.Progress == "" || cfg.Progress == bedrock.ProgressAuto) {
+		// A spinner would interleave with the JSON on a terminal
+		if err := cfg.Apply("flag --json", map[string]interface{}{"progress": bedrock.ProgressSilent}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// guidance returns the prompt rules for the --type and --scope hints after
+// checking them against the configured rules
+func (o *options) guidance(rules convention.Rules) ([]string, error) {
+	var lines []string
+	if o.typ != "" {
+		if !rules.AllowsType(o.typ) {
+			return nil, fmt.Errorf("--type %q is not one of the configured commit types: %s", o.typ, strings.Join(rules.Types, ", "))
+		}
+		lines = append(lines, fmt.Sprintf("Use the type %q for every commit", o.typ))
+	}
+	if o.scope != "" {
+		if !rules.UsesScope() || !rules.AllowsScope(o.scope) {
+			return nil, fmt.Errorf("--scope %q is not allowed by the configured commit rules", o.scope)
+		}
+		lines = append(lines, fmt.Sprintf("Use the scope %q for every commit", o.scope))
+	}
+	return lines, nil
 }
 
 // run is the main function that orchestrates the commit message generation
-func run() error {
+func run(ctx context.Context, opts *options) error {
+	if opts.version {
+		fmt.Println("gudcommit " + versionString())
+		return nil
+	}
+	if opts.noColor {
+		colorize = false
+	}
+	if opts.json {
+		// Keep stdout for the JSON result
+		status = os.Stderr
+		progressOut = os.Stderr
+	}
+
+	// Get the repository root path for config lookup and better context
+	repoPath := repoRoot(ctx)
+
+	cfg, err := config.Load(repoPath)
+	if err != nil {
+		return err
+	}
+	if err := applyFlags(cfg, opts); err != nil {
+		return err
+	}
+	if opts.showConfig {
+		return cfg.Print(os.Stdout)
+	}
+	guidance, err := opts.guidance(cfg.Commit)
+	if err != nil {
+		return err
+	}
+	if opts.amend {
+		if subject, err := gitOutput(ctx, "log", "-1", "--format=%s"); err == nil && subject != "" {
+			guidance = append(guidance, currentMessageGuidance(subject))
+		}
+	}
 
-	// Check for staged changes first
-	if err := checkStagedChanges(); err != nil {
-		fmt.Printf("❌ %v\n", err)
+	// Check for changes first
+	args := diffArgs(ctx, opts)
+	if err := checkStagedChanges(ctx, args); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Fprintf(status, "❌ %v\n", err)
 		return nil
 	}
 
 	// Get git diff
-	diffOutput, err := getGitDiff()
+	diffOutput, err := getGitDiff(ctx, args)
 	if err != nil {
 		return fmt.Errorf("failed to get git diff: %w", err)
 	}
 
 	// Check if diffOutput is empty
 	if strings.TrimSpace(diffOutput) == "" {
-		fmt.Println(">> No changes to commit.")
+		fmt.Fprintln(status, ">> No changes to commit.")
 		return nil
 	}
 
-	// Get the repository root path for better context
-	repoRoot, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
-	if err != nil {
-		repoRoot = []byte(".")
-	}
-	repoPath := strings.TrimSpace(string(repoRoot))
-
 	// Escape backslashes for JSON
 	diffOutput = strings.ReplaceAll(diffOutput, "\\", "\\\\")
 
 	// Generate commit message
-	fmt.Println("🤖 Generating commit message...")
-	completion, err := invokeBedrockModel(diffOutput, repoPath)
+	fmt.Fprintln(status, "🤖 Generating commit message...")
+	conv, completion, err := invokeModel(ctx, cfg, diffOutput, repoPath, guidance, opts.candidates)
 	if err != nil {
-		return fmt.Errorf("failed to invoke Bedrock model: %w", err)
+		var overCap *diff.OverCapError
+		if errors.As(err, &overCap) {
+			fmt.Fprintf(status, "❌ %v\n", err)
+			return nil
+		}
+		var secrets *redact.FoundError
+		if errors.As(err, &secrets) {
+			return err
+		}
+		return fmt.Errorf("failed to invoke model: %w", err)
 	}
 
-	if completion == "" {
-		fmt.Println("Sorry. No commit message could be generated.")
-		return nil
-	}
+	for {
+		candidates := parseCandidates(completion, cfg.Commit)
+		if len(candidates) == 0 {
+			fmt.Fprintln(status, "Sorry. No commit message could be generated.")
+			return nil
+		}
 
-	// Parse response
-	commitMessages, err := parseCommitResponse(completion)
-	if err != nil {
-		// Fallback to raw response
-		commitMessages = []string{strings.TrimSpace(completion)}
-	}
+		// Let the user pick one of several alternatives, or ask for more
+		chosen := candidates[0]
+		if len(candidates) > 1 {
+			showCandidates(candidates)
+			if opts.dryRun {
+				if opts.json {
+					return printJSON(jsonResult{Message: chosen.message, Commits: chosen.commits, Candidates: candidateMessages(candidates)})
+				}
+				return nil
+			}
+			if !opts.yes {
+				response, err := promptUser(ctx, fmt.Sprintf("Pick a message (1-%d), r to Regenerate, g <guidance> to regenerate with Guidance, or n to cancel: ", len(candidates)))
+				if err != nil {
+					return fmt.Errorf("failed to read user input: %w", err)
+				}
+				word, rest := splitAnswer(response)
+				if isRegenerate(word) {
+					if completion, err = regenerate(ctx, conv, word, rest, completion, opts.candidates); err != nil {
+						return err
+					}
+					continue
+				}
+				n, convErr := strconv.Atoi(word)
+				if convErr != nil || n < 1 || n > len(candidates) {
+					fmt.Fprintln(status, "Commit canceled.")
+					if opts.json {
+						return printJSON(jsonResult{Message: chosen.message, Commits: chosen.commits, Candidates: candidateMessages(candidates)})
+					}
+					return nil
+				}
+				chosen = candidates[n-1]
+			}
+		}
 
-	if len(commitMessages) == 0 {
-		fmt.Println("Sorry. No commit message could be generated.")
-		return nil
-	}
+		commits := chosen.commits
+		commitMessages := chosen.renderedMessages(cfg.Commit)
+
+		// Review on the full screen when the terminal allows it
+		if len(commits) > 0 && useReviewScreen(cfg, opts) {
+			rv, action, err := reviewCommits(ctx, cfg.Commit, commits, opts)
+			if err != nil {
+				return err
+			}
+			if rv != nil {
+				if action != actionRegenerate {
+					return rv.finish(ctx, repoPath, action, opts)
+				}
+				word := "r"
+				if rv.guidance != "" {
+					word = "g"
+				}
+				if completion, err = regenerate(ctx, conv, word, rv.guidance, completion, opts.candidates); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if opts.split && len(commits) > 0 {
+			committed, err := splitCommits(ctx, repoPath, commits, cfg.Commit, opts)
+			if err != nil || !opts.json {
+				return err
+			}
+			return printJSON(jsonResult{Message: strings.Join(commitMessages, "\n\n"), Commits: commits, Committed: committed})
+		}
 
-	// Display the generated message(s)
-	fmt.Println()
-	fmt.Println("📝 Generated commit message(s):")
+		// Display the generated message(s)
+		fmt.Fprintln(status)
+		fmt.Fprintln(status, "📝 Generated commit message(s):")
+
+		// Show all commit messages
+		for i, message := range commitMessages {
+			if len(commitMessages) > 1 {
+				fmt.Fprintln(status, bold(fmt.Sprintf("%d. %s", i+1, message)))
+			} else {
+				fmt.Fprintln(status, bold(message))
+			}
+		}
+		fmt.Fprintln(status)
 
-	// Show all commit messages
-	for i, message := range commitMessages {
+		// Create a comprehensive commit message
+		mainMessage := chosen.message
 		if len(commitMessages) > 1 {
-			fmt.Printf("\033[1m%d. %s\033[0m\n", i+1, message)
-		} else {
-			fmt.Printf("\033[1m%s\033[0m\n", message)
+			fmt.Fprintln(status, "📝 Combined commit message:")
+			fmt.Fprintln(status, bold(mainMessage))
+			fmt.Fprintln(status)
+		}
+
+		result := jsonResult{Message: mainMessage, Commits: commits}
+		if opts.dryRun {
+			if opts.json {
+				return printJSON(result)
+			}
+			return nil
+		}
+
+		// Prompt user for confirmation unless --yes was given
+		word, rest := "y", ""
+		if !opts.yes {
+			question := "Proceed with the commit? (y/n, e to Edit, r to Regenerate or g <guidance> to regenerate with Guidance): "
+			canSplit := len(commits) > 1 && !opts.all && !opts.amend
+			if canSplit {
+				question = "Proceed with the commit? (y/n, e to Edit, s to Split into separate commits, r to Regenerate or g <guidance> to regenerate with Guidance): "
+			}
+			response, err := promptUser(ctx, question)
+			if err != nil {
+				return fmt.Errorf("failed to read user input: %w", err)
+			}
+			word, rest = splitAnswer(response)
+			if (word == "s" || word == "split") && canSplit {
+				_, err := splitCommits(ctx, repoPath, commits, cfg.Commit, opts)
+				return err
+			}
 		}
+
+		switch {
+		case word == "y" || word == "yes":
+			// Execute git commit
+			if err := executeGitCommit(ctx, mainMessage, commitArgs(opts)...); err != nil {
+				return fmt.Errorf("failed to commit: %w", err)
+			}
+			fmt.Fprintln(status, "✅ Commit successful!")
+			result.Committed = true
+		case word == "e" || word == "edit":
+			// Execute git commit with editor
+			if err := executeGitCommitEdit(ctx, mainMessage, commitArgs(opts)...); err != nil {
+				return fmt.Errorf("failed to commit with editor: %w", err)
+			}
+		case isRegenerate(word):
+			if completion, err = regenerate(ctx, conv, word, rest, completion, opts.candidates); err != nil {
+				return err
+			}
+			continue
+		default:
+			fmt.Fprintln(status, "Commit canceled.")
+		}
+
+		if opts.json {
+			return printJSON(result)
+		}
+		return nil
 	}
-	fmt.Println()
+}
 
-	// Create a comprehensive commit message
-	var mainMessage string
-	if len(commitMessages) > 1 {
-		// Combine all messages into one comprehensive message
-		mainMessage = strings.Join(commitMessages, "\n")
-		fmt.Println("📝 Combined commit message:")
-		fmt.Printf("\033[1m%s\033[0m\n", mainMessage)
-		fmt.Println()
-	} else {
-		mainMessage = commitMessages[0]
+// combineMessages joins several messages into one comprehensive message,
+// keeping messages with a body apart as paragraphs
+func combineMessages(messages []string) string {
+	separator := "\n"
+	for _, message := range messages {
+		if strings.Contains(message, "\n") {
+			separator = "\n\n"
+		}
 	}
+	return strings.Join(messages, separator)
+}
 
-	// Prompt user for confirmation
-	response, err := promptUser(mainMessage)
-	if err != nil {
-		return fmt.Errorf("failed to read user input: %w", err)
+// jsonResult is what --json prints to stdout
+type jsonResult struct {
+	// Message is the combined commit message
+	Message   string          `json:"message"`
+	Commits   []CommitMessage `json:"commits"`
+	Committed bool            `json:"committed"`
+	// Candidates holds every alternative when --candidates asked for several
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// printJSON writes result to stdout
+func printJSON(result jsonResult) error {
+	if result.Commits == nil {
+		result.Commits = []CommitMessage{}
 	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
 
-	switch response {
-	case "y", "yes":
-		// Execute git commit
-		if err := executeGitCommit(mainMessage); err != nil {
-			return fmt.Errorf("failed to commit: %w", err)
-		}
-		fmt.Println("✅ Commit successful!")
-	case "e", "edit":
-		// Execute git commit with editor
-		if err := executeGitCommitEdit(mainMessage); err != nil {
-			return fmt.Errorf("failed to commit with editor: %w", err)
+// subcommands maps a first argument to the command it runs; anything else
+// is the default commit flow
+var subcommands = map[string]func(ctx context.Context, args []string) error{
+	"install": runInstall,
+	"hook":    runHook,
+	"lint":    runLint,
+	"reword":  runReword,
+}
+
+// dispatch runs the subcommand named by args[0], or the commit flow
+func dispatch(ctx context.Context, args []string) error {
+	colorize = os.Getenv("NO_COLOR") == "" && term.IsTerminal(os.Stdout)
+	if len(args) > 0 {
+		if cmd, ok := subcommands[args[0]]; ok {
+			return cmd(ctx, args[1:])
 		}
-	default:
-		fmt.Println("Commit canceled.")
 	}
-
-	return nil
+	opts, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+	return run(ctx, opts)
 }
 
 func main() {
-	if err := run(); err != nil {
+	// Cancel in-flight work on Ctrl-C or SIGTERM instead of dying mid-spinner
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := dispatch(ctx, os.Args[1:]); err != nil {
+		var usage usageError
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return
+		case errors.As(err, &usage):
+			// The error and usage have already been printed
+			os.Exit(2)
+		case ctx.Err() != nil:
+			stop()
+			fmt.Fprintln(status, "\n>> Canceled.")
+			os.Exit(term.ExitInterrupted)
+		}
 		log.Fatalf(">> %v", err)
 	}
 }
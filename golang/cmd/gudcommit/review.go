@@ -0,0 +1,672 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+	"github.com/gudlyf/GudCommit/golang/pkg/convention"
+	"github.com/gudlyf/GudCommit/golang/pkg/split"
+	"github.com/gudlyf/GudCommit/golang/pkg/term"
+)
+
+// fileStat is a changed file with its line counts
+type fileStat struct {
+	path    string
+	added   int
+	deleted int
+	binary  bool
+}
+
+// fileStats lists the files in the diff selected by args with their line
+// counts. Renames are listed as a deletion and an addition, as for split.
+func fileStats(ctx context.Context, args []string) ([]fileStat, error) {
+	args = append(append([]string(nil), args...), "--numstat", "--no-renames", "-z")
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed files: %w", err)
+	}
+	var stats []fileStat
+	for _, record := range strings.Split(string(out), "\x00") {
+		fields := strings.SplitN(record, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		stat := fileStat{path: fields[2], binary: fields[0] == "-"}
+		stat.added, _ = strconv.Atoi(fields[0])
+		stat.deleted, _ = strconv.Atoi(fields[1])
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// reviewAction is what the user chose on the review screen
+type reviewAction int
+
+const (
+	actionNone reviewAction = iota
+	actionCommit
+	actionEditor
+	actionRegenerate
+	actionCancel
+)
+
+// reviewMode is what key presses currently do
+type reviewMode int
+
+const (
+	modeBrowse reviewMode = iota
+	modeDescription
+	modeScope
+	modeGuidance
+	modePickType
+	modePickScope
+)
+
+// Panes of the review screen
+const (
+	paneFiles = iota
+	paneCommits
+)
+
+// review is the state of the review screen. It only changes through
+// handle, so the screen can be driven by tests without a terminal.
+type review struct {
+	rules   convention.Rules
+	commits []CommitMessage
+	files   []fileStat
+	// owners holds the index of the commit each file belongs to
+	owners   []int
+	included []bool
+	// partial allows leaving files out and committing them separately. It is
+	// off for --all and --amend, which commit more than the index.
+	partial  bool
+	separate bool
+
+	focus  int
+	file   int
+	commit int
+	mode   reviewMode
+	input  []rune
+	// options and choice drive the type and scope pickers
+	options []string
+	choice  int
+	// guidance is set when the user asked to regenerate with guidance
+	guidance string
+	// note is shown once below the panes
+	note string
+}
+
+// newReview starts a review of commits covering files
+func newReview(rules convention.Rules, commits []CommitMessage, files []fileStat, partial, separate bool) *review {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	claims := make([][]string, len(commits))
+	for i, c := range commits {
+		claims[i] = c.Files
+	}
+	r := &review{
+		rules:    rules,
+		commits:  append([]CommitMessage(nil), commits...),
+		files:    files,
+		owners:   split.Assign(len(commits), claims, paths),
+		included: make([]bool, len(files)),
+		partial:  partial,
+		separate: separate && partial && len(commits) > 1,
+	}
+	for i := range r.included {
+		r.included[i] = true
+	}
+	if len(files) == 0 {
+		r.focus = paneCommits
+	}
+	return r
+}
+
+// leavesOut reports whether any file was toggled off
+func (r *review) leavesOut() bool {
+	for _, in := range r.included {
+		if !in {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedCommits returns the indices of the commits that will be made:
+// those that own an included file, as split.Plan drops groups without
+// files, or all of them when there are no files to own
+func (r *review) selectedCommits() []int {
+	var selected []int
+	for i := range r.commits {
+		if len(r.files) == 0 {
+			selected = append(selected, i)
+			continue
+		}
+		for j, owner := range r.owners {
+			if owner == i && r.included[j] {
+				selected = append(selected, i)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// message returns the combined message of the selected commits
+func (r *review) message() string {
+	var messages []string
+	for _, i := range r.selectedCommits() {
+		messages = append(messages, r.commits[i].Render(r.rules.WrapWidth))
+	}
+	return combineMessages(messages)
+}
+
+// groups returns the commits to create from the included files: one per
+// commit when committing separately, otherwise a single combined one
+func (r *review) groups() []split.Group {
+	if !r.separate {
+		var files []string
+		for i, f := range r.files {
+			if r.included[i] {
+				files = append(files, f.path)
+			}
+		}
+		return []split.Group{{Message: r.message(), Files: files}}
+	}
+	var groups []split.Group
+	for _, i := range r.selectedCommits() {
+		g := split.Group{Message: r.commits[i].Render(r.rules.WrapWidth)}
+		for j, f := range r.files {
+			if r.owners[j] == i && r.included[j] {
+				g.Files = append(g.Files, f.path)
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// handle applies one key press and returns the action it completes, if any
+func (r *review) handle(key term.Key) reviewAction {
+	r.note = ""
+	switch r.mode {
+	case modeDescription, modeScope, modeGuidance:
+		return r.handleInput(key)
+	case modePickType, modePickScope:
+		r.handlePick(key)
+		return actionNone
+	}
+
+	switch key {
+	case term.KeyUp, 'k':
+		r.move(-1)
+	case term.KeyDown, 'j':
+		r.move(1)
+	case term.KeyTab, term.KeyLeft, term.KeyRight:
+		if len(r.files) > 0 {
+			r.focus = 1 - r.focus
+		}
+	case ' ':
+		r.toggleFile()
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		r.assignFile(int(key - '1'))
+	case 'e':
+		r.mode = modeDescription
+		r.input = []rune(r.commits[r.commit].Description)
+	case 't':
+		r.startPick(modePickType, r.rules.Types, r.commits[r.commit].Type)
+	case 's':
+		r.startScope()
+	case 'm':
+		switch {
+		case !r.partial:
+			r.note = "Separate commits are not available with --all or --amend"
+		case len(r.commits) < 2:
+			r.note = "There is only one commit"
+		default:
+			r.separate = !r.separate
+		}
+	case 'r':
+		return actionRegenerate
+	case 'g':
+		r.mode = modeGuidance
+		r.input = nil
+	case term.KeyEnter, 'c':
+		if len(r.files) > 0 && len(r.selectedCommits()) == 0 {
+			r.note = "No files are selected"
+			return actionNone
+		}
+		return actionCommit
+	case 'E':
+		if r.leavesOut() || r.separate {
+			r.note = "The editor commits everything as one commit; include every file and turn off separate commits first"
+			return actionNone
+		}
+		return actionEditor
+	case 'q', term.KeyEscape:
+		return actionCancel
+	}
+	return actionNone
+}
+
+// move changes the selection in the focused pane
+func (r *review) move(delta int) {
+	if r.focus == paneFiles {
+		r.file = clamp(r.file+delta, len(r.files))
+	} else {
+		r.commit = clamp(r.commit+delta, len(r.commits))
+	}
+}
+
+// toggleFile includes or leaves out the selected file
+func (r *review) toggleFile() {
+	switch {
+	case r.focus != paneFiles:
+		r.note = "Select a file first (Tab switches panes)"
+	case !r.partial:
+		r.note = "Files cannot be left out with --all or --amend"
+	default:
+		r.included[r.file] = !r.included[r.file]
+	}
+}
+
+// assignFile moves the selected file to commit n (zero-based)
+func (r *review) assignFile(n int) {
+	switch {
+	case r.focus != paneFiles:
+		r.note = "Select a file first (Tab switches panes)"
+	case !r.partial:
+		r.note = "Files cannot be moved with --all or --amend"
+	case n >= len(r.commits):
+		r.note = fmt.Sprintf("No commit %d", n+1)
+	default:
+		r.owners[r.file] = n
+	}
+}
+
+// startScope opens the scope picker, or a text input when any scope is
+// allowed
+func (r *review) startScope() {
+	current := r.commits[r.commit].Scope
+	switch {
+	case !r.rules.UsesScope():
+		r.note = "Scopes are turned off by the commit rules"
+	case len(r.rules.Scopes) > 0:
+		r.startPick(modePickScope, r.rules.Scopes, current)
+	default:
+		r.mode = modeScope
+		r.input = []rune(current)
+	}
+}
+
+// startPick opens a picker over options with current selected
+func (r *review) startPick(mode reviewMode, options []string, current string) {
+	r.mode = mode
+	r.options = options
+	r.choice = 0
+	for i, o := range options {
+		if o == current {
+			r.choice = i
+		}
+	}
+}
+
+// handlePick moves through or accepts a picker
+func (r *review) handlePick(key term.Key) {
+	switch key {
+	case term.KeyLeft, term.KeyUp, 'h', 'k':
+		r.choice = clamp(r.choice-1, len(r.options))
+	case term.KeyRight, term.KeyDown, term.KeyTab, 'l', 'j':
+		r.choice = clamp(r.choice+1, len(r.options))
+	case term.KeyEnter:
+		if r.mode == modePickType {
+			r.commits[r.commit].Type = r.options[r.choice]
+		} else {
+			r.commits[r.commit].Scope = r.options[r.choice]
+		}
+		r.mode = modeBrowse
+	case term.KeyEscape:
+		r.mode = modeBrowse
+	}
+}
+
+// handleInput edits the text input and applies it on Enter
+func (r *review) handleInput(key term.Key) reviewAction {
+	switch {
+	case key == term.KeyEscape:
+		r.mode = modeBrowse
+	case key == term.KeyBackspace:
+		if len(r.input) > 0 {
+			r.input = r.input[:len(r.input)-1]
+		}
+	case key == term.KeyEnter:
+		value := strings.TrimSpace(string(r.input))
+		switch {
+		case value == "":
+			r.note = "Type something, or press Esc to cancel"
+			return actionNone
+		case r.mode == modeDescription:
+			r.commits[r.commit].Description = value
+		case r.mode == modeScope && !r.rules.AllowsScope(value):
+			// Keep the input open so the scope can be corrected
+			r.note = fmt.Sprintf("Scope %q does not match the allowed patterns: %s", value, strings.Join(r.rules.ScopePatterns, ", "))
+			return actionNone
+		case r.mode == modeScope:
+			r.commits[r.commit].Scope = value
+		case r.mode == modeGuidance:
+			r.mode = modeBrowse
+			r.guidance = value
+			return actionRegenerate
+		}
+		r.mode = modeBrowse
+	case key >= ' ':
+		r.input = append(r.input, rune(key))
+	}
+	return actionNone
+}
+
+// clamp keeps i within [0, n)
+func clamp(i, n int) int {
+	if i >= n {
+		i = n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+// cell is one line of a pane; selected lines are highlighted when their
+// pane has focus
+type cell struct {
+	text     string
+	selected bool
+}
+
+// render draws the screen as lines of at most width columns
+func (r *review) render(width, height int) []string {
+	count, added, deleted := 0, 0, 0
+	for i, f := range r.files {
+		if r.included[i] {
+			count++
+			added += f.added
+			deleted += f.deleted
+		}
+	}
+	summary := fmt.Sprintf("%d file(s), +%d -%d", count, added, deleted)
+	if count < len(r.files) {
+		summary = fmt.Sprintf("%d of %d file(s), +%d -%d", count, len(r.files), added, deleted)
+	}
+	lines := []string{bold("gudcommit review") + "  " + summary, ""}
+
+	files, fileSel := r.filePane()
+	commits, commitSel := r.commitPane()
+	footer := r.footer(width)
+	rows := height - len(lines) - len(footer)
+	if rows < 3 {
+		rows = 3
+	}
+
+	if width >= 80 && len(r.files) > 0 {
+		left := width * 2 / 5
+		right := width - left - 3
+		files = window(files, fileSel, rows)
+		commits = window(commits, commitSel, rows)
+		for i := 0; i < len(files) || i < len(commits); i++ {
+			var a, b cell
+			if i < len(files) {
+				a = files[i]
+			}
+			if i < len(commits) {
+				b = commits[i]
+			}
+			lines = append(lines, r.style(a, left, r.focus == paneFiles, true)+" │ "+r.style(b, right, r.focus == paneCommits, false))
+		}
+	} else {
+		// Too narrow for two columns: stack the panes
+		half := rows / 2
+		if len(r.files) == 0 {
+			half = 0
+		}
+		for _, c := range window(files, fileSel, half) {
+			lines = append(lines, r.style(c, width, r.focus == paneFiles, false))
+		}
+		for _, c := range window(commits, commitSel, rows-half) {
+			lines = append(lines, r.style(c, width, r.focus == paneCommits, false))
+		}
+	}
+
+	for _, line := range footer {
+		lines = append(lines, truncate(line, width))
+	}
+	return lines
+}
+
+// filePane returns the lines of the file list and the selected line
+func (r *review) filePane() ([]cell, int) {
+	cells := []cell{{text: "Files"}}
+	for i, f := range r.files {
+		check := "[x]"
+		if !r.included[i] {
+			check = "[ ]"
+		}
+		owner := ""
+		if len(r.commits) > 1 {
+			owner = fmt.Sprintf("%d ", r.owners[i]+1)
+		}
+		stats := fmt.Sprintf("+%d -%d", f.added, f.deleted)
+		if f.binary {
+			stats = "binary"
+		}
+		cells = append(cells, cell{text: fmt.Sprintf("%s %s%s  %s", check, owner, f.path, stats), selected: i == r.file})
+	}
+	return cells, r.file + 1
+}
+
+// commitPane returns the lines of the proposed commits and the line of the
+// selected commit's header
+func (r *review) commitPane() ([]cell, int) {
+	title := "Commit message"
+	if r.separate {
+		title = "Separate commits"
+	}
+	cells := []cell{{text: title}}
+	selectedLine := 0
+	chosen := map[int]bool{}
+	for _, i := range r.selectedCommits() {
+		chosen[i] = true
+	}
+	for i, c := range r.commits {
+		if i > 0 {
+			cells = append(cells, cell{})
+		}
+		rendered := strings.Split(c.Render(r.rules.WrapWidth), "\n")
+		header := rendered[0]
+		if len(r.commits) > 1 {
+			header = fmt.Sprintf("%d. %s", i+1, header)
+		}
+		if !chosen[i] {
+			header += "  (no files, left out)"
+		}
+		if i == r.commit {
+			selectedLine = len(cells)
+		}
+		cells = append(cells, cell{text: header, selected: i == r.commit})
+		for _, line := range rendered[1:] {
+			cells = append(cells, cell{text: "   " + line})
+		}
+	}
+	return cells, selectedLine
+}
+
+// footer returns the note, the input line and the key help for the
+// current mode, with the help wrapped at width
+func (r *review) footer(width int) []string {
+	lines := r.footerLines()
+	help := lines[len(lines)-1]
+	lines = lines[:len(lines)-1]
+	line := ""
+	for _, item := range strings.Split(help, " · ") {
+		switch {
+		case line == "":
+			line = item
+		case utf8.RuneCountInString(line)+3+utf8.RuneCountInString(item) <= width:
+			line += " · " + item
+		default:
+			lines = append(lines, line)
+			line = item
+		}
+	}
+	return append(lines, line)
+}
+
+// footerLines returns the footer before wrapping
+func (r *review) footerLines() []string {
+	note := ""
+	if r.note != "" {
+		note = "❌ " + r.note
+	}
+	switch r.mode {
+	case modeDescription:
+		return []string{note, "Description: " + string(r.input) + "_", "Enter to apply · Esc to cancel"}
+	case modeScope:
+		return []string{note, "Scope: " + string(r.input) + "_", "Enter to apply · Esc to cancel"}
+	case modeGuidance:
+		return []string{note, "Guidance: " + string(r.input) + "_", "Enter to regenerate · Esc to cancel"}
+	case modePickType, modePickScope:
+		label := "Type:"
+		if r.mode == modePickScope {
+			label = "Scope:"
+		}
+		options := make([]string, len(r.options))
+		for i, o := range r.options {
+			if i == r.choice {
+				o = "[" + o + "]"
+			}
+			options[i] = o
+		}
+		return []string{note, label + " " + strings.Join(options, " "), "←/→ to choose · Enter to apply · Esc to cancel"}
+	}
+	return []string{note, "↑/↓ move · Tab switch pane · Space include file · 1-9 move file to commit · e edit · t type · s scope · " +
+		"m separate commits · r regenerate · g guidance · E editor · Enter commit · q quit"}
+}
+
+// style truncates and, when pad is set, pads c to width, and highlights it
+// when it is selected in the focused pane
+func (r *review) style(c cell, width int, focused, pad bool) string {
+	prefix := "  "
+	if c.selected {
+		prefix = "▸ "
+	}
+	text := truncate(prefix+c.text, width)
+	if pad {
+		text += strings.Repeat(" ", width-utf8.RuneCountInString(text))
+	}
+	if c.selected && focused {
+		return bold(text)
+	}
+	return text
+}
+
+// window returns at most n lines of cells, scrolled so that line selected
+// is visible; the first line is a title and always kept
+func window(cells []cell, selected, n int) []cell {
+	if n <= 0 || len(cells) == 0 {
+		return nil
+	}
+	if len(cells) <= n {
+		return cells
+	}
+	start := 1
+	if selected >= n {
+		start = selected - n + 2
+	}
+	return append([]cell{cells[0]}, cells[start:start+n-1]...)
+}
+
+// truncate shortens s to width columns, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+// useReviewScreen reports whether to review with the full-screen review
+// instead of the line prompt
+func useReviewScreen(cfg *config.Config, opts *options) bool {
+	return cfg.Review == config.ReviewTUI && !opts.yes && !opts.dryRun && !opts.json &&
+		term.IsTerminal(os.Stdin) && term.IsTerminal(os.Stdout)
+}
+
+// reviewCommits shows the review screen for commits until the user commits,
+// regenerates or cancels. It returns a nil review when the terminal cannot
+// show the screen, so the caller falls back to the line prompt.
+func reviewCommits(ctx context.Context, rules convention.Rules, commits []CommitMessage, opts *options) (*review, reviewAction, error) {
+	files, err := fileStats(ctx, diffArgs(ctx, opts))
+	if err != nil {
+		return nil, actionNone, err
+	}
+	restore, err := term.CharMode(os.Stdin)
+	if err != nil {
+		return nil, actionNone, nil
+	}
+	defer restore()
+
+	// Draw on the alternate screen so the shell's scrollback is untouched
+	fmt.Fprint(status, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(status, "\033[?25h\033[?1049l")
+
+	r := newReview(rules, commits, files, !opts.all && !opts.amend, opts.split)
+	for {
+		width, height := term.Size(os.Stdin)
+		fmt.Fprint(status, "\033[H\033[2J"+strings.Join(r.render(width, height), "\n"))
+		key, err := term.ReadKey(ctx)
+		if err != nil {
+			return nil, actionNone, err
+		}
+		if action := r.handle(key); action != actionNone {
+			return r, action, nil
+		}
+	}
+}
+
+// finish carries out the action chosen on the review screen
+func (r *review) finish(ctx context.Context, repoPath string, action reviewAction, opts *options) error {
+	message := r.message()
+	switch action {
+	case actionCommit:
+		fmt.Fprintln(status, bold(message))
+		fmt.Fprintln(status)
+		if r.leavesOut() || r.separate {
+			groups := r.groups()
+			if err := split.Commit(ctx, repoPath, groups); err != nil {
+				return fmt.Errorf("failed to create commits, staged changes were restored: %w", err)
+			}
+			fmt.Fprintf(status, "✅ Created %d commit(s)!\n", len(groups))
+			return nil
+		}
+		if err := executeGitCommit(ctx, message, commitArgs(opts)...); err != nil {
+			return fmt.Errorf("failed to commit: %w", err)
+		}
+		fmt.Fprintln(status, "✅ Commit successful!")
+	case actionEditor:
+		if err := executeGitCommitEdit(ctx, message, commitArgs(opts)...); err != nil {
+			return fmt.Errorf("failed to commit with editor: %w", err)
+		}
+	default:
+		fmt.Fprintln(status, "Commit canceled.")
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+	"github.com/gudlyf/GudCommit/golang/pkg/split"
+	"github.com/gudlyf/GudCommit/golang/pkg/term"
+)
+
+func testReview(partial bool) *review {
+	rules := config.Defaults().Commit
+	commits := []CommitMessage{
+		{Type: "feat", Scope: "api", Description: "add routes", Body: "Routes are served under /v2.", Files: []string{"api/routes.go"}},
+		{Type: "docs", Scope: "readme", Description: "describe routes", Files: []string{"README.md"}},
+	}
+	files := []fileStat{
+		{path: "api/routes.go", added: 40, deleted: 2},
+		{path: "README.md", added: 5},
+		{path: "api/logo.png", binary: true},
+	}
+	return newReview(rules, commits, files, partial, false)
+}
+
+// press sends keys and returns the last action
+func press(r *review, keys ...term.Key) reviewAction {
+	action := actionNone
+	for _, key := range keys {
+		action = r.handle(key)
+	}
+	return action
+}
+
+// typeText turns s into key presses
+func typeText(s string) []term.Key {
+	var keys []term.Key
+	for _, c := range s {
+		keys = append(keys, term.Key(c))
+	}
+	return keys
+}
+
+func TestReviewFiles(t *testing.T) {
+	r := testReview(true)
+	if !reflect.DeepEqual(r.owners, []int{0, 1, 0}) {
+		t.Fatalf("Expected files assigned to their commits, got %v", r.owners)
+	}
+
+	// Leave out the logo and move the README to the first commit
+	press(r, term.KeyDown, term.KeyDown, ' ', term.KeyUp, '1')
+	expected := []split.Group{{
+		Message: "feat(api): add routes\n\nRoutes are served under /v2.",
+		Files:   []string{"api/routes.go", "README.md"},
+	}}
+	if got := r.groups(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+	if r.note != "" {
+		t.Errorf("Unexpected note %q", r.note)
+	}
+
+	// Separate commits keep each commit's own files
+	press(r, '2', 'm')
+	groups := r.groups()
+	if len(groups) != 2 || !reflect.DeepEqual(groups[1].Files, []string{"README.md"}) || groups[1].Message != "docs(readme): describe routes" {
+		t.Errorf("Unexpected separate commits: %+v", groups)
+	}
+
+	// Nothing to commit once every file is left out
+	press(r, term.KeyUp, ' ', term.KeyDown, ' ')
+	if action := press(r, term.KeyEnter); action != actionNone || r.note == "" {
+		t.Errorf("Expected a note instead of committing, got %v", action)
+	}
+
+	// --all and --amend commit everything
+	r = testReview(false)
+	press(r, ' ')
+	if r.leavesOut() || r.note == "" {
+		t.Error("Expected files to stay included without partial commits")
+	}
+	if press(r, 'm'); r.separate {
+		t.Error("Expected separate commits to stay off without partial commits")
+	}
+}
+
+func TestReviewCommitWithoutFiles(t *testing.T) {
+	r := testReview(true)
+	// Move the README, the only file of the second commit, to the first
+	press(r, term.KeyDown, '1', 'm')
+	expected := []split.Group{{
+		Message: "feat(api): add routes\n\nRoutes are served under /v2.",
+		Files:   []string{"api/routes.go", "README.md", "api/logo.png"},
+	}}
+	if got := r.groups(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected only the commit with files, got %+v", got)
+	}
+	cells, _ := r.commitPane()
+	var pane []string
+	for _, c := range cells {
+		pane = append(pane, c.text)
+	}
+	if text := strings.Join(pane, "\n"); !strings.Contains(text, "2. docs(readme): describe routes  (no files, left out)") {
+		t.Errorf("Expected the second commit shown as left out, got:\n%s", text)
+	}
+
+	// The combined message leaves it out too
+	press(r, 'm')
+	if got := r.groups(); len(got) != 1 || got[0].Message != expected[0].Message {
+		t.Errorf("Expected the combined message without the second commit, got %+v", got)
+	}
+}
+
+func TestReviewEditing(t *testing.T) {
+	r := testReview(true)
+	press(r, term.KeyTab, term.KeyDown)
+
+	// Inline edit of the description
+	keys := append([]term.Key{'e'}, repeat(term.KeyBackspace, len("describe routes"))...)
+	keys = append(keys, typeText("document the routes")...)
+	press(r, append(keys, term.KeyEnter)...)
+	if got := r.commits[1].Description; got != "document the routes" {
+		t.Errorf("Expected edited description, got %q", got)
+	}
+
+	// Pick a type; Escape leaves it alone
+	press(r, 't', term.KeyRight, term.KeyEscape)
+	if r.commits[1].Type != "docs" || r.mode != modeBrowse {
+		t.Errorf("Expected escape to cancel the picker, got %q", r.commits[1].Type)
+	}
+	press(r, 't', term.KeyRight, term.KeyEnter)
+	if r.commits[1].Type != "style" {
+		t.Errorf("Expected the type after docs, got %q", r.commits[1].Type)
+	}
+
+	// Any scope is allowed, so it is typed in
+	press(r, append(append([]term.Key{'s'}, repeat(term.KeyBackspace, 6)...), append(typeText("docs"), term.KeyEnter)...)...)
+	if r.commits[1].Scope != "docs" {
+		t.Errorf("Expected typed scope, got %q", r.commits[1].Scope)
+	}
+
+	// A typed scope must match the configured patterns
+	r.rules.ScopePatterns = []string{"^[a-z]+$"}
+	press(r, append(append([]term.Key{'s'}, repeat(term.KeyBackspace, 4)...), append(typeText("Docs!"), term.KeyEnter)...)...)
+	if r.commits[1].Scope != "docs" || r.mode != modeScope || !strings.Contains(r.note, `"Docs!"`) {
+		t.Errorf("Expected the scope to be refused with a note, got %q (note %q)", r.commits[1].Scope, r.note)
+	}
+	if footer := strings.Join(r.footerLines(), "\n"); !strings.Contains(footer, "❌ Scope \"Docs!\" does not match") {
+		t.Errorf("Expected the error in the footer, got:\n%s", footer)
+	}
+	press(r, append(repeat(term.KeyBackspace, 5), append(typeText("docs"), term.KeyEnter)...)...)
+	if r.commits[1].Scope != "docs" || r.mode != modeBrowse || r.note != "" {
+		t.Errorf("Expected the corrected scope, got %q", r.commits[1].Scope)
+	}
+	r.rules.ScopePatterns = nil
+
+	// A configured scope list is offered as a picker
+	r.rules.Scopes = []string{"api", "docs", "readme"}
+	press(r, 's', term.KeyRight, term.KeyEnter)
+	if r.commits[1].Scope != "readme" {
+		t.Errorf("Expected picked scope, got %q", r.commits[1].Scope)
+	}
+
+	if got := r.message(); got != "feat(api): add routes\n\nRoutes are served under /v2.\n\nstyle(readme): document the routes" {
+		t.Errorf("Unexpected message:\n%s", got)
+	}
+}
+
+func TestReviewActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []term.Key
+		action   reviewAction
+		guidance string
+	}{
+		{name: "Commit", keys: []term.Key{term.KeyEnter}, action: actionCommit},
+		{name: "Editor", keys: []term.Key{'E'}, action: actionEditor},
+		{name: "Editor with a file left out", keys: []term.Key{' ', 'E'}, action: actionNone},
+		{name: "Regenerate", keys: []term.Key{'r'}, action: actionRegenerate},
+		{name: "Guidance", keys: append(append([]term.Key{'g'}, typeText("mention v2")...), term.KeyEnter), action: actionRegenerate, guidance: "mention v2"},
+		{name: "Empty guidance", keys: []term.Key{'g', ' ', term.KeyEnter}, action: actionNone},
+		{name: "Quit", keys: []term.Key{'q'}, action: actionCancel},
+		{name: "Escape", keys: []term.Key{term.KeyEscape}, action: actionCancel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testReview(true)
+			if action := press(r, tt.keys...); action != tt.action || r.guidance != tt.guidance {
+				t.Errorf("Expected %v %q, got %v %q", tt.action, tt.guidance, action, r.guidance)
+			}
+		})
+	}
+}
+
+func TestReviewRender(t *testing.T) {
+	old := colorize
+	colorize = false
+	defer func() { colorize = old }()
+
+	r := testReview(true)
+	press(r, term.KeyDown, ' ')
+
+	for _, width := range []int{120, 60} {
+		lines := r.render(width, 30)
+		screen := strings.Join(lines, "\n")
+		for _, want := range []string{"2 of 3 file(s), +40 -2", "[x] 1 api/routes.go  +40 -2", "[ ] 2 README.md  +5 -0", "api/logo.png  binary",
+			"1. feat(api): add routes", "Routes are served under /v2.", "2. docs(readme): describe routes  (no files, left out)"} {
+			if !strings.Contains(screen, want) {
+				t.Errorf("Expected %q at width %d in:\n%s", want, width, screen)
+			}
+		}
+		if sideBySide := strings.Contains(screen, " │ "); sideBySide != (width >= 80) {
+			t.Errorf("Expected side by side panes only on wide terminals, width %d:\n%s", width, screen)
+		}
+		for _, line := range lines {
+			if n := utf8.RuneCountInString(line); n > width {
+				t.Errorf("Line is %d columns, wider than %d: %q", n, width, line)
+			}
+		}
+	}
+
+	// Short terminals scroll the file list to keep the selection visible
+	r.files = append(r.files, make([]fileStat, 20)...)
+	r.included = append(r.included, make([]bool, 20)...)
+	r.owners = append(r.owners, make([]int, 20)...)
+	r.files[22].path = "last.go"
+	press(r, repeat(term.KeyDown, 30)...)
+	if lines := r.render(120, 12); len(lines) > 12 || !strings.Contains(strings.Join(lines, "\n"), "last.go") {
+		t.Errorf("Expected the selected file on a short screen:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestReviewFinish(t *testing.T) {
+	dir := newRepo(t)
+	out := captureStatus(t)
+	gitRun(t, dir, "config", "user.name", "Test")
+	gitRun(t, dir, "config", "user.email", "test@example.com")
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\nb\n"), 0o644)
+	gitRun(t, dir, "add", "b.txt")
+
+	opts := &options{}
+	files, err := fileStats(context.Background(), diffArgs(context.Background(), opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(files, []fileStat{{path: "a.txt", added: 1}, {path: "b.txt", added: 2}}) {
+		t.Fatalf("Unexpected file stats: %+v", files)
+	}
+
+	r := newReview(config.Defaults().Commit, []CommitMessage{{Type: "feat", Scope: "a", Description: "add a"}}, files, true, false)
+	press(r, term.KeyDown, ' ')
+	if err := r.finish(context.Background(), dir, actionCommit, opts); err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, out)
+	}
+	if got := gitRun(t, dir, "log", "--format=%s", "--name-only"); strings.TrimSpace(got) != "feat(a): add a\n\na.txt" {
+		t.Errorf("Expected a commit of a.txt only, got %q", got)
+	}
+	if got := gitRun(t, dir, "status", "--porcelain"); strings.TrimSpace(got) != "A  b.txt" {
+		t.Errorf("Expected b.txt to stay staged, got %q", got)
+	}
+}
+
+func repeat(key term.Key, n int) []term.Key {
+	keys := make([]term.Key, n)
+	for i := range keys {
+		keys[i] = key
+	}
+	return keys
+}
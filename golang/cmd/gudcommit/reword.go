@@ -0,0 +1,552 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/config"
+)
+
+// rewordDir is where reword keeps the rebase todo and the new messages,
+// relative to the git directory
+const rewordDir = "gudcommit-reword"
+
+// While gudcommit stands in for the editors during a rebase, these hold the
+// user's own editors when they asked to review the new messages or the todo
+const (
+	rewordEditorEnv = "GUDCOMMIT_EDITOR"
+	todoEditorEnv   = "GUDCOMMIT_SEQUENCE_EDITOR"
+)
+
+// rewording is a commit and the message proposed for it
+type rewording struct {
+	sha     string
+	subject string
+	// message is empty when no new message could be generated
+	message string
+}
+
+// runReword writes new messages for existing commits
+func runReword(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("gudcommit reword", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gudcommit reword [flags] <commit>\n       gudcommit reword [flags] <base>..[HEAD]\n\n"+
+			"Generate a new message for an existing commit from its changes, or for every commit in a\n"+
+			"range ending at HEAD, and rewrite the branch with git rebase.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	var yes, dryRun bool
+	fs.BoolVar(&yes, "yes", false, "rewrite without prompting")
+	fs.BoolVar(&yes, "y", false, "shorthand for --yes")
+	fs.BoolVar(&dryRun, "dry-run", false, "print the new messages, or the rebase todo for a range, without rewriting")
+	if err := fs.Parse(args); err != nil {
+		return usageFailure(err)
+	}
+	if fs.NArg() != 1 {
+		err := fmt.Errorf("expected one commit or range")
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return usageError{err}
+	}
+
+	repoPath := repoRoot(ctx)
+	cfg, err := config.Load(repoPath)
+	if err != nil {
+		return err
+	}
+
+	rev := fs.Arg(0)
+	include, exclude, err := resolveRevision(ctx, rev)
+	if err != nil {
+		return err
+	}
+	if len(include) > 1 || len(exclude) > 0 {
+		return rewordRange(ctx, cfg, repoPath, rev, include, exclude, yes, dryRun)
+	}
+	return rewordCommit(ctx, cfg, repoPath, rev, yes, dryRun)
+}
+
+// rewordCommit proposes a new message for one commit and, once accepted,
+// amends HEAD or rebases to change an older commit
+func rewordCommit(ctx context.Context, cfg *config.Config, repoPath, rev string, yes, dryRun bool) error {
+	sha, err := resolveCommit(ctx, rev)
+	if err != nil {
+		return err
+	}
+	head, err := resolveCommit(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	if sha != head {
+		if exec.CommandContext(ctx, "git", "merge-base", "--is-ancestor", sha, "HEAD").Run() != nil {
+			return fmt.Errorf("%s is not on the current branch", rev)
+		}
+		if _, revRange := rebaseUpstream(ctx, sha); revRange != "" {
+			if err := checkNoMerges(ctx, revRange, revRange); err != nil {
+				return err
+			}
+		}
+	}
+	subject, err := gitOutput(ctx, "log", "-1", "--format=%s", sha)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(status, "🤖 Generating a new message for %s %s...\n", sha[:7], subject)
+	conv, completion, err := generateReword(ctx, cfg, repoPath, sha, subject)
+	if err != nil {
+		return err
+	}
+
+	for {
+		candidates := parseCandidates(completion, cfg.Commit)
+		if len(candidates) == 0 {
+			fmt.Fprintln(status, "Sorry. No commit message could be generated.")
+			return nil
+		}
+		message := candidates[0].message
+
+		fmt.Fprintln(status)
+		fmt.Fprintf(status, "📝 New message for %s (was %q):\n", sha[:7], subject)
+		fmt.Fprintln(status, bold(message))
+		fmt.Fprintln(status)
+		if dryRun {
+			return nil
+		}
+
+		word, rest := "y", ""
+		if !yes {
+			response, err := promptUser(ctx, "Reword the commit? (y/n, e to Edit, r to Regenerate or g <guidance> to regenerate with Guidance): ")
+			if err != nil {
+				return fmt.Errorf("failed to read user input: %w", err)
+			}
+			word, rest = splitAnswer(response)
+		}
+
+		switch {
+		case word == "y" || word == "yes" || word == "e" || word == "edit":
+			edit := word == "e" || word == "edit"
+			if sha == head {
+				return amendMessage(ctx, message, edit)
+			}
+			return rebaseRewordings(ctx, []rewording{{sha: sha, subject: subject, message: message}}, false, edit)
+		case isRegenerate(word):
+			if completion, err = regenerate(ctx, conv, word, rest, completion, 1); err != nil {
+				return err
+			}
+		default:
+			fmt.Fprintln(status, "Reword canceled.")
+			return nil
+		}
+	}
+}
+
+// rewordRange proposes new messages for every commit in the range rev,
+// resolved into the revisions it includes and excludes by resolveRevision.
+// The range must end at HEAD and hold only commits on the current branch;
+// the branch is rebased with a todo that rewords each of them.
+func rewordRange(ctx context.Context, cfg *config.Config, repoPath, rev string, include, exclude []string, yes, dryRun bool) error {
+	head, err := resolveCommit(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	endsAtHead := false
+	revs := make([]string, 0, len(include)+len(exclude))
+	for _, name := range include {
+		sha, err := resolveCommit(ctx, name)
+		if err != nil {
+			return err
+		}
+		endsAtHead = endsAtHead || sha == head
+		revs = append(revs, sha)
+	}
+	for _, name := range exclude {
+		revs = append(revs, "^"+name)
+	}
+	if !endsAtHead {
+		return fmt.Errorf("the range must end at HEAD, the commit being rewritten")
+	}
+	// In base...HEAD, base's side must already be part of the branch
+	off, err := gitOutput(ctx, append(append([]string{"rev-list"}, revs...), "^"+head)...)
+	if err != nil {
+		return err
+	}
+	if off != "" {
+		return fmt.Errorf("%s has commits that are not on the current branch", rev)
+	}
+	if err := checkNoMerges(ctx, rev, revs...); err != nil {
+		return err
+	}
+	out, err := gitOutput(ctx, append([]string{"rev-list", "--reverse"}, revs...)...)
+	if err != nil {
+		return err
+	}
+	shas := strings.Fields(out)
+	if len(shas) == 0 {
+		fmt.Fprintf(status, ">> No commits in %s.\n", rev)
+		return nil
+	}
+
+	rewordings := make([]rewording, len(shas))
+	for i, sha := range shas {
+		subject, err := gitOutput(ctx, "log", "-1", "--format=%s", sha)
+		if err != nil {
+			return err
+		}
+		rewordings[i] = rewording{sha: sha, subject: subject}
+
+		fmt.Fprintf(status, "🤖 [%d/%d] %s %s\n", i+1, len(shas), sha[:7], subject)
+		_, completion, err := generateReword(ctx, cfg, repoPath, sha, subject)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Keep the commit as it is rather than give up on the range
+			fmt.Fprintf(status, "⚠ :: Keeping the message of %s: %v\n", sha[:7], err)
+			continue
+		}
+		if candidates := parseCandidates(completion, cfg.Commit); len(candidates) > 0 {
+			rewordings[i].message = candidates[0].message
+		}
+	}
+
+	todo := rewordTodo(rewordings)
+	if dryRun {
+		fmt.Print(todo)
+		return nil
+	}
+	fmt.Fprintln(status)
+	fmt.Fprintln(status, "📝 Rebase todo:")
+	fmt.Fprint(status, todo)
+	fmt.Fprintln(status)
+
+	response := "y"
+	if !yes {
+		response, err = promptUser(ctx, fmt.Sprintf("Rewrite %d commit(s)? (y/n or e to Edit the todo first): ", len(shas)))
+		if err != nil {
+			return fmt.Errorf("failed to read user input: %w", err)
+		}
+	}
+	switch word, _ := splitAnswer(response); word {
+	case "y", "yes":
+		return rebaseRewordings(ctx, rewordings, false, false)
+	case "e", "edit":
+		return rebaseRewordings(ctx, rewordings, true, true)
+	default:
+		fmt.Fprintln(status, "Reword canceled.")
+		return nil
+	}
+}
+
+// generateReword asks the model for a message describing the changes in
+// commit sha, mentioning its current subject
+func generateReword(ctx context.Context, cfg *config.Config, repoPath, sha, subject string) (*conversation, string, error) {
+	diffOutput, err := getGitDiff(ctx, []string{"show", "--format=", "--patch", sha})
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.TrimSpace(diffOutput) == "" {
+		return nil, "", fmt.Errorf("commit %s has no changes to describe", sha[:7])
+	}
+	// Escape backslashes for JSON
+	diffOutput = strings.ReplaceAll(diffOutput, "\\", "\\\\")
+
+	// The subject goes to the model too, so it is redacted like the diff
+	subject, err = redactText(cfg, subject)
+	if err != nil {
+		return nil, "", err
+	}
+	guidance := []string{currentMessageGuidance(subject), "Write exactly one commit entry covering all of the changes"}
+	return invokeModel(ctx, cfg, diffOutput, repoPath, guidance, 1)
+}
+
+// currentMessageGuidance tells the model about the message being replaced
+func currentMessageGuidance(subject string) string {
+	return fmt.Sprintf("The commit's current subject is %q; keep any detail from it that the changes support", subject)
+}
+
+// rewordTodo returns a rebase todo that rewords the commits with a new
+// message, each followed by that message as comments, and picks the rest.
+// Commits are named by their full SHA, which cannot become ambiguous.
+func rewordTodo(rewordings []rewording) string {
+	var b strings.Builder
+	for _, r := range rewordings {
+		if r.message == "" {
+			fmt.Fprintf(&b, "pick %s %s\n", r.sha, r.subject)
+			continue
+		}
+		header, _, _ := strings.Cut(r.message, "\n")
+		fmt.Fprintf(&b, "reword %s %s\n", r.sha, header)
+		fmt.Fprintf(&b, "#   was: %s\n", r.subject)
+		for _, line := range strings.Split(r.message, "\n")[1:] {
+			fmt.Fprintln(&b, strings.TrimRight("#   "+line, " "))
+		}
+	}
+	return b.String()
+}
+
+// amendMessage replaces the message of HEAD, leaving staged changes alone
+func amendMessage(ctx context.Context, message string, edit bool) error {
+	if edit {
+		if err := executeGitCommitEdit(ctx, message, "--amend", "--only"); err != nil {
+			return fmt.Errorf("failed to reword with editor: %w", err)
+		}
+		return nil
+	}
+	if err := executeGitCommit(ctx, message, "--amend", "--only"); err != nil {
+		return fmt.Errorf("failed to reword: %w", err)
+	}
+	fmt.Fprintln(status, "✅ Reworded HEAD")
+	return nil
+}
+
+// rebaseRewordings rewrites the branch from the parent of the first commit,
+// giving each commit with a message its new message. gudcommit stands in
+// for the sequence editor, writing the prepared todo, and for the editor,
+// writing each new message. The user's editor is opened on the todo with
+// reviewTodo and on each message with reviewMessages.
+func rebaseRewordings(ctx context.Context, rewordings []rewording, reviewTodo, reviewMessages bool) error {
+	dir, err := gitOutput(ctx, "rev-parse", "--git-path", rewordDir)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to prepare reword: %w", err)
+	}
+	// Pick every commit after the first one reworded, as git would
+	first := rewordings[0].sha
+	upstream, revRange := rebaseUpstream(ctx, first)
+	if revRange == "" {
+		revRange = "HEAD"
+	}
+	out, err := gitOutput(ctx, "rev-list", "--reverse", revRange)
+	if err != nil {
+		return err
+	}
+	messages := map[string]rewording{}
+	for _, r := range rewordings {
+		messages[r.sha] = r
+		if r.message != "" {
+			if err := os.WriteFile(filepath.Join(dir, r.sha), []byte(r.message+"\n"), 0o644); err != nil {
+				return fmt.Errorf("failed to prepare reword: %w", err)
+			}
+		}
+	}
+	var todo []rewording
+	for _, sha := range strings.Fields(out) {
+		r, ok := messages[sha]
+		if !ok {
+			subject, _ := gitOutput(ctx, "log", "-1", "--format=%s", sha)
+			r = rewording{sha: sha, subject: subject}
+		}
+		todo = append(todo, r)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "git-rebase-todo"), []byte(rewordTodo(todo)), 0o644); err != nil {
+		return fmt.Errorf("failed to prepare reword: %w", err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the gudcommit executable: %w", err)
+	}
+	env := append(os.Environ(),
+		"GIT_SEQUENCE_EDITOR="+shellQuote(exe)+" hook sequence-editor",
+		"GIT_EDITOR="+shellQuote(exe)+" hook reword-editor",
+	)
+	// Look the editors up before they are replaced
+	if reviewTodo {
+		editor, err := gitOutput(ctx, "var", "GIT_SEQUENCE_EDITOR")
+		if err != nil {
+			if editor, err = gitOutput(ctx, "var", "GIT_EDITOR"); err != nil {
+				return fmt.Errorf("failed to find your editor: %w", err)
+			}
+		}
+		env = append(env, todoEditorEnv+"="+editor)
+	}
+	if reviewMessages {
+		editor, err := gitOutput(ctx, "var", "GIT_EDITOR")
+		if err != nil {
+			return fmt.Errorf("failed to find your editor: %w", err)
+		}
+		env = append(env, rewordEditorEnv+"="+editor)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"rebase", "-i", "--autostash"}, upstream)...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git rebase failed; finish it with git rebase --continue or undo it with git rebase --abort: %w", err)
+	}
+	os.RemoveAll(dir)
+
+	reworded := 0
+	for _, r := range rewordings {
+		if r.message != "" {
+			reworded++
+		}
+	}
+	fmt.Fprintf(status, "✅ Reworded %d commit(s)\n", reworded)
+	return nil
+}
+
+// rebaseUpstream returns the rebase argument that starts the rebase at sha,
+// and the range of commits it rewrites; the range is empty when sha is a
+// root commit and the whole history is rewritten
+func rebaseUpstream(ctx context.Context, sha string) (string, string) {
+	if exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", sha+"^").Run() != nil {
+		return "--root", ""
+	}
+	return sha + "^", sha + "^..HEAD"
+}
+
+// checkNoMerges fails when the commits revs select, named name in the
+// error, contain merges, which a rebase would flatten
+func checkNoMerges(ctx context.Context, name string, revs ...string) error {
+	merges, err := gitOutput(ctx, append([]string{"rev-list", "--merges"}, revs...)...)
+	if err != nil {
+		return err
+	}
+	if merges != "" {
+		return fmt.Errorf("%s contains merge commits, which reword cannot rewrite", name)
+	}
+	return nil
+}
+
+// sequenceEditor replaces the todo git wrote in file with the one reword
+// prepared, opening the user's editor on it when they asked to review
+func sequenceEditor(ctx context.Context, file string) error {
+	dir, err := gitOutput(ctx, "rev-parse", "--git-path", rewordDir)
+	if err != nil {
+		return err
+	}
+	todo, err := os.ReadFile(filepath.Join(dir, "git-rebase-todo"))
+	if err != nil {
+		return fmt.Errorf("no reword in progress: %w", err)
+	}
+	if err := os.WriteFile(file, todo, 0o644); err != nil {
+		return err
+	}
+	if editor := os.Getenv(todoEditorEnv); editor != "" {
+		return runEditor(ctx, editor, file)
+	}
+	return nil
+}
+
+// rewordEditor writes the new message for the commit git is rewording into
+// file. Commits without one keep the message git wrote.
+func rewordEditor(ctx context.Context, file string) error {
+	done, err := gitOutput(ctx, "rev-parse", "--git-path", "rebase-merge/done")
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(done)
+	if err != nil {
+		return fmt.Errorf("no rebase in progress: %w", err)
+	}
+	// The last command done is the one being carried out
+	var last []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") {
+			last = fields
+		}
+	}
+	if len(last) == 0 {
+		return reviewInEditor(ctx, file)
+	}
+	sha, err := resolveCommit(ctx, last[1])
+	if err != nil {
+		return reviewInEditor(ctx, file)
+	}
+	dir, err := gitOutput(ctx, "rev-parse", "--git-path", rewordDir)
+	if err != nil {
+		return err
+	}
+	message, err := os.ReadFile(filepath.Join(dir, sha))
+	if errors.Is(err, os.ErrNotExist) {
+		return reviewInEditor(ctx, file)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, message, 0o644); err != nil {
+		return err
+	}
+	return reviewInEditor(ctx, file)
+}
+
+// reviewInEditor opens the user's editor on file when they asked to review
+// the new messages
+func reviewInEditor(ctx context.Context, file string) error {
+	if editor := os.Getenv(rewordEditorEnv); editor != "" {
+		return runEditor(ctx, editor, file)
+	}
+	return nil
+}
+
+// runEditor opens editor on file
+func runEditor(ctx context.Context, editor, file string) error {
+	// git runs editors through the shell, so do the same
+	cmd := exec.CommandContext(ctx, "sh", "-c", editor+` "$@"`, editor, file)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// resolveRevision resolves rev, a commit or a range such as base..HEAD or
+// base...HEAD, with git rev-parse. It returns the revisions rev includes and
+// the ones a range excludes, as full SHAs.
+func resolveRevision(ctx context.Context, rev string) ([]string, []string, error) {
+	out, err := gitOutput(ctx, "rev-parse", "--revs-only", "--end-of-options", rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	var include, exclude []string
+	for _, line := range strings.Fields(out) {
+		if sha, ok := strings.CutPrefix(line, "^"); ok {
+			exclude = append(exclude, sha)
+		} else {
+			include = append(include, line)
+		}
+	}
+	if len(include) == 0 {
+		return nil, nil, fmt.Errorf("unknown commit or range %s", rev)
+	}
+	return include, exclude, nil
+}
+
+// resolveCommit returns the full name of the commit rev points to
+func resolveCommit(ctx context.Context, rev string) (string, error) {
+	sha, err := gitOutput(ctx, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil || sha == "" {
+		return "", fmt.Errorf("unknown commit %s", rev)
+	}
+	return sha, nil
+}
+
+// gitOutput runs git and returns its trimmed output
+func gitOutput(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,69 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultAnthropicEndpoint = "https://api.anthropic.com"
+
+// AnthropicRequest represents the request payload for the Anthropic Messages API
+type AnthropicRequest struct {
+	Model     string    `json:"model"`
+	MaxTokens int       `json:"max_tokens"`
+	Messages  []Message `json:"messages"`
+}
+
+// AnthropicProvider calls the Anthropic Messages API directly
+type AnthropicProvider struct {
+	APIKey string
+	Config Config
+	reporting
+}
+
+func newAnthropicProvider(cfg Config) (*AnthropicProvider, error) {
+	apiKey, envName := apiKeyFromEnv(cfg, ProviderAnthropic)
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s environment variable is not set", envName)
+	}
+	return &AnthropicProvider{APIKey: apiKey, Config: cfg}, nil
+}
+
+// Name returns the provider name
+func (p *AnthropicProvider) Name() string {
+	return "Anthropic"
+}
+
+// Generate sends the prompt to the Messages API
+func (p *AnthropicProvider) Generate(ctx context.Context, prompt string, opts Options) (string, error) {
+	base := p.Config.Endpoint
+	if base == "" {
+		base = defaultAnthropicEndpoint
+	}
+	endpoint := strings.TrimRight(base, "/") + "/v1/messages"
+
+	payload := AnthropicRequest{
+		Model:     p.Config.ModelID,
+		MaxTokens: maxTokens(opts, p.Config),
+		Messages:  messages(prompt, opts),
+	}
+	headers := map[string]string{
+		"x-api-key":         p.APIKey,
+		"anthropic-version": "2023-06-01",
+	}
+
+	// The Messages API returns the same content blocks as Bedrock
+	var response BedrockResponse
+	if err := postJSON(ctx, newCall(p.Name(), endpoint, headers, p.Config, p.progress()), payload, &response); err != nil {
+		return "", err
+	}
+
+	if len(response.Content) > 0 {
+		p.progress().Report(Event{Kind: EventReceived, Provider: p.Name(), Time: time.Now()})
+		return response.Content[0].Text, nil
+	}
+
+	return "", fmt.Errorf("no content in response")
+}
@@ -1,12 +1,11 @@
 package bedrock
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
-	"os"
+	"strings"
 	"time"
 )
 
@@ -14,6 +13,12 @@ const (
 	DefaultAWSRegion = "us-east-1"
 )
 
+// Supported values for the "auth" config key
+const (
+	AuthBearer = "bearer"
+	AuthSigV4  = "sigv4"
+)
+
 // BedrockRequest represents the request payload for Bedrock
 type BedrockRequest struct {
 	AnthropicVersion string    `json:"anthropic_version"`
@@ -45,121 +50,237 @@ type Usage struct {
 	OutputTokens int `json:"output_tokens"`
 }
 
-// Client represents a Bedrock API client
+// Client represents a Bedrock API client. Requests are signed with SigV4
+// when Signer is set and use the bearer APIKey otherwise.
 type Client struct {
 	APIKey string
 	Region string
+	Config Config
+	Signer *Signer
+	reporting
 }
 
-// NewClient creates a new Bedrock client
-func NewClient() (*Client, error) {
-	apiKey := os.Getenv("GUD_BEDROCK_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GUD_BEDROCK_API_KEY environment variable is not set")
-	}
+// NewClient creates a Bedrock client for cfg, usually the one loaded by
+// config.Load
+func NewClient(cfg Config) (*Client, error) {
+	return newBedrockClient(cfg)
+}
 
-	region := os.Getenv("AWS_REGION")
-	if region == "" {
-		region = DefaultAWSRegion
+// newBedrockClient picks bearer-token or SigV4 authentication from cfg.Auth.
+// Without an explicit choice the API key is preferred when it is set.
+func newBedrockClient(cfg Config) (*Client, error) {
+	apiKey, envName := apiKeyFromEnv(cfg, ProviderBedrock)
+
+	switch strings.ToLower(cfg.Auth) {
+	case AuthBearer:
+		if apiKey == "" {
+			return nil, fmt.Errorf("%s environment variable is not set", envName)
+		}
+	case AuthSigV4:
+		return newSigV4Client(cfg)
+	case "":
+		if apiKey == "" {
+			client, err := newSigV4Client(cfg)
+			if err != nil {
+				return nil, fmt.Errorf("%s environment variable is not set and no AWS credentials were found: %w", envName, err)
+			}
+			return client, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth %q (expected %s or %s)", cfg.Auth, AuthBearer, AuthSigV4)
 	}
 
 	return &Client{
 		APIKey: apiKey,
-		Region: region,
+		Region: cfg.Region,
+		Config: cfg,
 	}, nil
 }
 
-// InvokeModel invokes Bedrock directly using API key authentication
-func (c *Client) InvokeModel(prompt, repoPath string) (string, error) {
-	// Load dynamic configuration (model ID, timeout, region)
-	cfg, err := loadConfig()
+// newSigV4Client creates a client that signs requests with AWS credentials
+func newSigV4Client(cfg Config) (*Client, error) {
+	creds, err := LoadCredentials(cfg.AWSProfile)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return &Client{
+		Region: cfg.Region,
+		Config: cfg,
+		Signer: &Signer{Credentials: creds, Region: cfg.Region, Service: bedrockService},
+	}, nil
+}
 
-	// Construct the Bedrock endpoint for Claude
-	endpoint := fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com/model/%s/invoke", cfg.Region, cfg.ModelID)
+// Name returns the provider name
+func (c *Client) Name() string {
+	return "Bedrock"
+}
 
-	// Create the request payload for Claude
-	payload := BedrockRequest{
-		AnthropicVersion: "bedrock-2023-05-31",
-		MaxTokens:        2048,
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
+// InvokeModel invokes Bedrock directly using the client's authentication
+func (c *Client) InvokeModel(prompt, repoPath string) (string, error) {
+	return c.Generate(context.Background(), prompt, Options{})
+}
+
+// Generate invokes the configured Bedrock model with the prompt
+func (c *Client) Generate(ctx context.Context, prompt string, opts Options) (string, error) {
+	cfg := c.config()
+
+	var response BedrockResponse
+	if err := postJSON(ctx, c.call(cfg, "invoke"), c.payload(prompt, opts, cfg), &response); err != nil {
+		return "", err
 	}
 
-	// Marshal to JSON
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	// Extract the content from Claude's response
+	if len(response.Content) > 0 {
+		c.progress().Report(Event{Kind: EventReceived, Provider: c.Name(), Time: time.Now()})
+		return response.Content[0].Text, nil
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonPayload))
+	return "", fmt.Errorf("no content in response")
+}
+
+// StreamModel invokes the model through invoke-with-response-stream, calling
+// onDelta with each text fragment as it arrives. It returns the full text.
+func (c *Client) StreamModel(ctx context.Context, prompt string, opts Options, onDelta func(string)) (string, error) {
+	cfg := c.config()
+
+	call := c.call(cfg, "invoke-with-response-stream")
+	resp, err := doRequest(ctx, streamingClient(cfg.TimeoutSeconds), call, c.payload(prompt, opts, cfg))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers for API key authentication
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-
-	// Create a simple spinner that updates in place with timer
-	spinnerChars := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
-	done := make(chan bool)
-	startTime := time.Now()
-
-	// Start spinner animation in a goroutine
-	go func() {
-		i := 0
-		for {
-			select {
-			case <-done:
-				return
-			default:
-				elapsed := time.Since(startTime)
-				seconds := int(elapsed.Seconds())
-				fmt.Printf("\r\033[K%s :: Awaiting response from Bedrock ... [%ds]", spinnerChars[i%len(spinnerChars)], seconds)
-				os.Stdout.Sync()
-				i++
-				time.Sleep(100 * time.Millisecond)
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	for {
+		msg, err := readEventMessage(resp.Body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return full.String(), ctx.Err()
 			}
+			return full.String(), fmt.Errorf("failed to read response stream: %w", err)
 		}
-	}()
 
-	// Make the request
-	client := &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second}
-	resp, err := client.Do(req)
+		if msg.Headers[":message-type"] == "exception" {
+			errType := msg.Headers[":exception-type"]
+			_, message := parseErrorBody(msg.Payload)
+			return full.String(), &APIError{Provider: c.Name(), Type: errType, Message: message, kind: classifyError(0, errType)}
+		}
+		if msg.Headers[":event-type"] != "chunk" {
+			continue
+		}
 
-	// Signal completion and clear the spinner line
-	done <- true
-	fmt.Print("\r\033[K") // Clear the spinner line
+		event, err := decodeStreamChunk(msg.Payload)
+		if err != nil {
+			return full.String(), err
+		}
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta.Text != "" {
+				full.WriteString(event.Delta.Text)
+				if onDelta != nil {
+					onDelta(event.Delta.Text)
+				}
+			}
+		case "message_stop":
+			c.progress().Report(Event{Kind: EventReceived, Provider: c.Name(), Time: time.Now(), Streamed: true})
+			return full.String(), nil
+		}
+	}
 
-	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+	if full.Len() == 0 {
+		return "", fmt.Errorf("no content in response")
 	}
-	defer resp.Body.Close()
+	c.progress().Report(Event{Kind: EventReceived, Provider: c.Name(), Time: time.Now(), Streamed: true})
+	return full.String(), nil
+}
+
+// StreamEvent represents an Anthropic streaming event carried in a Bedrock chunk
+type StreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("bedrock API error: %d - %s", resp.StatusCode, string(body))
+// decodeStreamChunk unwraps the base64 "bytes" field of a Bedrock chunk event
+func decodeStreamChunk(payload []byte) (StreamEvent, error) {
+	var chunk struct {
+		Bytes []byte `json:"bytes"`
+	}
+	var event StreamEvent
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return event, fmt.Errorf("failed to decode stream chunk: %w", err)
 	}
+	if err := json.Unmarshal(chunk.Bytes, &event); err != nil {
+		return event, fmt.Errorf("failed to decode stream event: %w", err)
+	}
+	return event, nil
+}
 
-	// Parse response
-	var response BedrockResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+// call describes a request to the model endpoint for action
+func (c *Client) call(cfg Config, action string) apiCall {
+	headers, sign := c.auth(cfg)
+	var keyEnv string
+	if sign == nil {
+		keyEnv = apiKeyEnv(cfg, ProviderBedrock)
+	}
+	return apiCall{
+		name:     c.Name(),
+		endpoint: c.endpoint(cfg, action),
+		headers:  headers,
+		sign:     sign,
+		keyEnv:   keyEnv,
+		timeout:  cfg.TimeoutSeconds,
+		retry:    cfg.Retry,
+		reporter: c.progress(),
 	}
+}
 
-	// Extract the content from Claude's response
-	if len(response.Content) > 0 {
-		fmt.Print("✔ :: Response received from Bedrock\n")
-		return response.Content[0].Text, nil
+// endpoint builds the model URL for action. Model IDs and inference profile
+// ARNs contain ':' and '/' so they are escaped as one segment.
+func (c *Client) endpoint(cfg Config, action string) string {
+	base := fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", cfg.Region)
+	if cfg.Endpoint != "" {
+		base = strings.TrimRight(cfg.Endpoint, "/")
 	}
+	return fmt.Sprintf("%s/model/%s/%s", base, uriEncode(cfg.ModelID), action)
+}
 
-	return "", fmt.Errorf("no content in response")
+// payload creates the request payload for Claude
+func (c *Client) payload(prompt string, opts Options, cfg Config) BedrockRequest {
+	return BedrockRequest{
+		AnthropicVersion: "bedrock-2023-05-31",
+		MaxTokens:        maxTokens(opts, cfg),
+		Messages:         messages(prompt, opts),
+	}
+}
+
+// auth returns the bearer header or SigV4 signer for a request
+func (c *Client) auth(cfg Config) (map[string]string, signFunc) {
+	if c.Signer == nil {
+		return map[string]string{"Authorization": "Bearer " + c.APIKey}, nil
+	}
+	signer := *c.Signer
+	if signer.Region == "" {
+		signer.Region = cfg.Region
+	}
+	return nil, signer.Sign
+}
+
+// config returns the client's configuration, or the defaults when the
+// client was constructed without one.
+func (c *Client) config() Config {
+	if c.Config.ModelID != "" {
+		return c.Config
+	}
+	cfg := DefaultConfig()
+	cfg.ModelID = DefaultModelID(cfg.Provider)
+	if c.Region != "" {
+		cfg.Region = c.Region
+	}
+	return cfg
 }
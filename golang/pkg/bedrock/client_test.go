@@ -257,3 +257,23 @@ func BenchmarkBedrockResponseMarshal(b *testing.B) {
 		json.Marshal(response)
 	}
 }
+
+func TestNewClient(t *testing.T) {
+	t.Setenv("GUD_BEDROCK_API_KEY", "test-api-key")
+	cfg := DefaultConfig()
+	cfg.ModelID = "test-model"
+	cfg.Region = "eu-west-1"
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if client.APIKey != "test-api-key" || client.Region != "eu-west-1" || client.config().ModelID != "test-model" {
+		t.Errorf("Expected the client to use the given config, got %+v", client)
+	}
+
+	// A client built without a config uses the defaults
+	bare := &Client{APIKey: "key", Region: "ap-south-1"}
+	if got := bare.config(); got.ModelID != DefaultModelID(ProviderBedrock) || got.Region != "ap-south-1" {
+		t.Errorf("Unexpected default config %+v", got)
+	}
+}
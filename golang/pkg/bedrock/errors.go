@@ -0,0 +1,203 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error kinds returned by providers; match them with errors.Is
+var (
+	ErrThrottled     = errors.New("request throttled")
+	ErrAuth          = errors.New("authentication failed")
+	ErrValidation    = errors.New("request rejected as invalid")
+	ErrModelNotFound = errors.New("model not found")
+	ErrModelNotReady = errors.New("model not ready")
+	ErrServer        = errors.New("service unavailable")
+)
+
+// hints gives the user a next step for each error kind
+var hints = map[error]string{
+	ErrThrottled:     "the model is rate limited; wait a moment or request a quota increase",
+	ErrValidation:    "the request was rejected; the diff may be too large for the model's context window",
+	ErrModelNotFound: "check model_id and region; many Claude models must be invoked through an inference profile ID",
+	ErrModelNotReady: "the model is still loading; try again shortly",
+	ErrServer:        "the service had a transient failure; try again shortly",
+}
+
+// APIError describes a failed model API call
+type APIError struct {
+	Provider   string
+	StatusCode int
+	// Type is the service error code, e.g. ThrottlingException
+	Type       string
+	Message    string
+	RetryAfter time.Duration
+	kind       error
+	// keyEnv names the variable the API key came from, if one was used
+	keyEnv string
+}
+
+// Error formats the failure with an actionable hint
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s API error", strings.ToLower(e.Provider))
+	if e.StatusCode > 0 {
+		fmt.Fprintf(&b, ": %d", e.StatusCode)
+	}
+	if e.Type != "" {
+		fmt.Fprintf(&b, " %s", e.Type)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, " - %s", e.Message)
+	}
+	hint := hints[e.kind]
+	if e.kind == ErrAuth {
+		hint = e.authHint()
+	}
+	if hint != "" {
+		fmt.Fprintf(&b, " (%s)", hint)
+	}
+	return b.String()
+}
+
+// authHint names the credentials to check after an authentication failure:
+// the variable the API key was read from and, for Bedrock, the AWS
+// credentials
+func (e *APIError) authHint() string {
+	bedrock := strings.EqualFold(e.Provider, ProviderBedrock)
+	switch {
+	case bedrock && e.keyEnv != "":
+		return fmt.Sprintf("check %s (short-term keys expire after 12 hours) or your AWS credentials", e.keyEnv)
+	case bedrock:
+		return "check your AWS credentials"
+	case e.keyEnv != "":
+		return "check " + e.keyEnv
+	}
+	return "check your API key"
+}
+
+// Unwrap returns the error kind so callers can use errors.Is
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// Retryable reports whether the request may succeed if sent again
+func (e *APIError) Retryable() bool {
+	return e.kind == ErrThrottled || e.kind == ErrModelNotReady || e.kind == ErrServer
+}
+
+// newAPIError builds an APIError from a non-200 HTTP response
+func newAPIError(provider string, resp *http.Response, body []byte) *APIError {
+	errType, message := parseErrorBody(body)
+
+	// Bedrock reports the error code in a header as "Code:namespace"
+	if header := resp.Header.Get("X-Amzn-Errortype"); header != "" {
+		errType, _, _ = strings.Cut(header, ":")
+	}
+
+	e := &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Type:       errType,
+		Message:    message,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	e.kind = classifyError(e.StatusCode, e.Type)
+	return e
+}
+
+// classifyError maps an HTTP status and service error code to an error kind
+func classifyError(status int, errType string) error {
+	t := strings.ToLower(errType)
+	switch {
+	case strings.Contains(t, "modelnotready"):
+		return ErrModelNotReady
+	case strings.Contains(t, "throttl"), strings.Contains(t, "toomanyrequests"), strings.Contains(t, "rate_limit"),
+		status == http.StatusTooManyRequests:
+		return ErrThrottled
+	case strings.Contains(t, "accessdenied"), strings.Contains(t, "unrecognizedclient"), strings.Contains(t, "expiredtoken"),
+		strings.Contains(t, "authentication"), strings.Contains(t, "permission"),
+		status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return ErrAuth
+	case strings.Contains(t, "resourcenotfound"), strings.Contains(t, "not_found"), status == http.StatusNotFound:
+		return ErrModelNotFound
+	case strings.Contains(t, "validation"), strings.Contains(t, "invalid_request"),
+		status == http.StatusBadRequest, status == http.StatusRequestEntityTooLarge, status == http.StatusUnprocessableEntity:
+		return ErrValidation
+	case strings.Contains(t, "serviceunavailable"), strings.Contains(t, "internalserver"), strings.Contains(t, "modeltimeout"),
+		strings.Contains(t, "overloaded"), status == http.StatusRequestTimeout, status >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// parseErrorBody extracts an error code and message from the JSON error
+// shapes used by Bedrock, Anthropic, OpenAI and Ollama. Unknown bodies are
+// returned verbatim as the message.
+func parseErrorBody(body []byte) (string, string) {
+	var parsed struct {
+		Type     string          `json:"__type"`
+		Message  string          `json:"message"`
+		MessageU string          `json:"Message"`
+		Error    json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", strings.TrimSpace(string(body))
+	}
+
+	message := parsed.Message
+	if message == "" {
+		message = parsed.MessageU
+	}
+	errType := parsed.Type
+	if i := strings.LastIndex(errType, "#"); i >= 0 {
+		errType = errType[i+1:]
+	}
+
+	if len(parsed.Error) > 0 {
+		var nested struct {
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		var plain string
+		if json.Unmarshal(parsed.Error, &nested) == nil {
+			if nested.Message != "" {
+				message = nested.Message
+			}
+			if nested.Type != "" {
+				errType = nested.Type
+			} else if nested.Code != "" {
+				errType = nested.Code
+			}
+		} else if json.Unmarshal(parsed.Error, &plain) == nil {
+			message = plain
+		}
+	}
+
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	return errType, message
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package bedrock
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Sizes of the fixed parts of an AWS event stream message
+const (
+	eventPreludeLen = 12
+	eventCRCLen     = 4
+	maxEventLen     = 16 * 1024 * 1024
+)
+
+// eventMessage is a single decoded message from an AWS event stream
+type eventMessage struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// readEventMessage reads one message in the application/vnd.amazon.eventstream
+// binary framing: a 12-byte prelude (total length, headers length, prelude CRC),
+// the headers, the payload and a trailing CRC over the whole message.
+func readEventMessage(r io.Reader) (eventMessage, error) {
+	prelude := make([]byte, eventPreludeLen)
+	if _, err := io.ReadFull(r, prelude); err != nil {
+		return eventMessage{}, err
+	}
+
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return eventMessage{}, fmt.Errorf("event stream prelude checksum mismatch")
+	}
+	if totalLen < eventPreludeLen+eventCRCLen+headersLen || totalLen > maxEventLen {
+		return eventMessage{}, fmt.Errorf("invalid event stream message length %d", totalLen)
+	}
+
+	rest := make([]byte, totalLen-eventPreludeLen)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return eventMessage{}, fmt.Errorf("truncated event stream message: %w", err)
+	}
+
+	body := rest[:len(rest)-eventCRCLen]
+	crc := crc32.NewIEEE()
+	crc.Write(prelude)
+	crc.Write(body)
+	if crc.Sum32() != binary.BigEndian.Uint32(rest[len(rest)-eventCRCLen:]) {
+		return eventMessage{}, fmt.Errorf("event stream message checksum mismatch")
+	}
+
+	headers, err := decodeEventHeaders(body[:headersLen])
+	if err != nil {
+		return eventMessage{}, err
+	}
+
+	return eventMessage{Headers: headers, Payload: body[headersLen:]}, nil
+}
+
+// decodeEventHeaders decodes the header block, keeping string-typed values
+// and skipping the other value types.
+func decodeEventHeaders(b []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	r := bytes.NewReader(b)
+	for r.Len() > 0 {
+		nameLen, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		name := make([]byte, nameLen)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, fmt.Errorf("truncated event header name: %w", err)
+		}
+		valueType, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("truncated event header type: %w", err)
+		}
+
+		var skip int64
+		switch valueType {
+		case 0, 1: // bool true / false
+		case 2: // byte
+			skip = 1
+		case 3: // short
+			skip = 2
+		case 4: // int
+			skip = 4
+		case 5, 8: // long, timestamp
+			skip = 8
+		case 9: // uuid
+			skip = 16
+		case 6, 7: // byte array, string
+			var valueLen uint16
+			if err := binary.Read(r, binary.BigEndian, &valueLen); err != nil {
+				return nil, fmt.Errorf("truncated event header length: %w", err)
+			}
+			value := make([]byte, valueLen)
+			if _, err := io.ReadFull(r, value); err != nil {
+				return nil, fmt.Errorf("truncated event header value: %w", err)
+			}
+			if valueType == 7 {
+				headers[string(name)] = string(value)
+			}
+		default:
+			return nil, fmt.Errorf("unknown event header type %d", valueType)
+		}
+		if int64(r.Len()) < skip {
+			return nil, fmt.Errorf("truncated event header value")
+		}
+		if _, err := r.Seek(skip, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+	return headers, nil
+}
@@ -0,0 +1,144 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// encodeEventMessage builds an event stream frame with string headers
+func encodeEventMessage(headers map[string]string, payload []byte) []byte {
+	var h bytes.Buffer
+	for name, value := range headers {
+		h.WriteByte(byte(len(name)))
+		h.WriteString(name)
+		h.WriteByte(7)
+		binary.Write(&h, binary.BigEndian, uint16(len(value)))
+		h.WriteString(value)
+	}
+
+	total := uint32(eventPreludeLen + h.Len() + len(payload) + eventCRCLen)
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.BigEndian, total)
+	binary.Write(&msg, binary.BigEndian, uint32(h.Len()))
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(h.Bytes())
+	msg.Write(payload)
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+// chunkEvent wraps an Anthropic stream event the way Bedrock does
+func chunkEvent(event string) []byte {
+	payload := fmt.Sprintf(`{"bytes":"%s"}`, base64.StdEncoding.EncodeToString([]byte(event)))
+	return encodeEventMessage(map[string]string{
+		":event-type":   "chunk",
+		":message-type": "event",
+		":content-type": "application/json",
+	}, []byte(payload))
+}
+
+func textDelta(text string) []byte {
+	return chunkEvent(fmt.Sprintf(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":%q}}`, text))
+}
+
+func TestReadEventMessage(t *testing.T) {
+	frame := encodeEventMessage(map[string]string{":event-type": "chunk"}, []byte("payload"))
+
+	msg, err := readEventMessage(bytes.NewReader(frame))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if msg.Headers[":event-type"] != "chunk" {
+		t.Errorf("Expected event type 'chunk', got %q", msg.Headers[":event-type"])
+	}
+	if string(msg.Payload) != "payload" {
+		t.Errorf("Expected payload 'payload', got %q", msg.Payload)
+	}
+}
+
+func TestReadEventMessageChecksum(t *testing.T) {
+	frame := encodeEventMessage(map[string]string{":event-type": "chunk"}, []byte("payload"))
+	frame[len(frame)-6] ^= 0xff
+
+	if _, err := readEventMessage(bytes.NewReader(frame)); err == nil {
+		t.Errorf("Expected checksum error for corrupted frame")
+	}
+}
+
+func TestStreamModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/invoke-with-response-stream") {
+			t.Errorf("Expected streaming endpoint, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.Write(chunkEvent(`{"type":"message_start"}`))
+		w.Write(textDelta("feat: "))
+		w.Write(textDelta("add streaming"))
+		w.Write(chunkEvent(`{"type":"message_stop"}`))
+	}))
+	defer server.Close()
+
+	client := &Client{APIKey: "key", Config: Config{ModelID: "test-model", Endpoint: server.URL, TimeoutSeconds: 5}}
+
+	var deltas []string
+	result, err := client.StreamModel(context.Background(), "prompt", Options{}, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "feat: add streaming" {
+		t.Errorf("Expected full text, got %q", result)
+	}
+	if len(deltas) != 2 {
+		t.Errorf("Expected 2 deltas, got %d: %v", len(deltas), deltas)
+	}
+}
+
+func TestStreamModelException(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(textDelta("partial"))
+		w.Write(encodeEventMessage(map[string]string{
+			":message-type":   "exception",
+			":exception-type": "throttlingException",
+		}, []byte(`{"message":"Too many requests"}`)))
+	}))
+	defer server.Close()
+
+	client := &Client{APIKey: "key", Config: Config{ModelID: "test-model", Endpoint: server.URL, TimeoutSeconds: 5}}
+	result, err := client.StreamModel(context.Background(), "prompt", Options{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "throttlingException") {
+		t.Errorf("Expected throttling error, got %v", err)
+	}
+	if result != "partial" {
+		t.Errorf("Expected partial text to be returned, got %q", result)
+	}
+}
+
+func TestGenerateStreamFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":{"role":"assistant","content":"whole response"},"done":true}`))
+	}))
+	defer server.Close()
+
+	p := &OllamaProvider{Config: Config{ModelID: "test-model", Endpoint: server.URL, TimeoutSeconds: 5}}
+
+	var deltas []string
+	result, err := GenerateStream(context.Background(), p, "prompt", Options{}, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "whole response" || len(deltas) != 1 || deltas[0] != "whole response" {
+		t.Errorf("Expected single delta with whole response, got %q %v", result, deltas)
+	}
+}
@@ -0,0 +1,72 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultOllamaEndpoint = "http://localhost:11434"
+
+// OllamaRequest represents the request payload for Ollama's /api/chat
+type OllamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  OllamaOptions `json:"options"`
+}
+
+// OllamaOptions holds the model parameters understood by Ollama
+type OllamaOptions struct {
+	NumPredict int `json:"num_predict"`
+}
+
+// OllamaResponse represents a non-streaming /api/chat response
+type OllamaResponse struct {
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+}
+
+// OllamaProvider calls a local Ollama-style HTTP server
+type OllamaProvider struct {
+	Config Config
+	reporting
+}
+
+func newOllamaProvider(cfg Config) *OllamaProvider {
+	return &OllamaProvider{Config: cfg}
+}
+
+// Name returns the provider name
+func (p *OllamaProvider) Name() string {
+	return "Ollama"
+}
+
+// Generate sends the prompt to the local chat endpoint
+func (p *OllamaProvider) Generate(ctx context.Context, prompt string, opts Options) (string, error) {
+	base := p.Config.Endpoint
+	if base == "" {
+		base = defaultOllamaEndpoint
+	}
+	endpoint := strings.TrimRight(base, "/") + "/api/chat"
+
+	payload := OllamaRequest{
+		Model:    p.Config.ModelID,
+		Messages: messages(prompt, opts),
+		Stream:   false,
+		Options:  OllamaOptions{NumPredict: maxTokens(opts, p.Config)},
+	}
+
+	var response OllamaResponse
+	if err := postJSON(ctx, newCall(p.Name(), endpoint, nil, p.Config, p.progress()), payload, &response); err != nil {
+		return "", err
+	}
+
+	if response.Message.Content != "" {
+		p.progress().Report(Event{Kind: EventReceived, Provider: p.Name(), Time: time.Now()})
+		return response.Message.Content, nil
+	}
+
+	return "", fmt.Errorf("no content in response")
+}
@@ -0,0 +1,86 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultOpenAIEndpoint = "https://api.openai.com/v1"
+
+// OpenAIRequest represents an OpenAI-compatible chat completions request
+type OpenAIRequest struct {
+	Model     string    `json:"model"`
+	MaxTokens int       `json:"max_tokens"`
+	Messages  []Message `json:"messages"`
+}
+
+// OpenAIResponse represents an OpenAI-compatible chat completions response
+type OpenAIResponse struct {
+	Choices []OpenAIChoice `json:"choices"`
+	Usage   OpenAIUsage    `json:"usage"`
+}
+
+// OpenAIChoice represents a single completion choice
+type OpenAIChoice struct {
+	Message Message `json:"message"`
+}
+
+// OpenAIUsage represents token usage information
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// OpenAIProvider calls any OpenAI-compatible chat completions endpoint
+type OpenAIProvider struct {
+	APIKey string
+	Config Config
+	reporting
+}
+
+func newOpenAIProvider(cfg Config) (*OpenAIProvider, error) {
+	apiKey, envName := apiKeyFromEnv(cfg, ProviderOpenAI)
+	// Self-hosted compatible servers often run without authentication
+	if apiKey == "" && cfg.Endpoint == "" {
+		return nil, fmt.Errorf("%s environment variable is not set", envName)
+	}
+	return &OpenAIProvider{APIKey: apiKey, Config: cfg}, nil
+}
+
+// Name returns the provider name
+func (p *OpenAIProvider) Name() string {
+	return "OpenAI"
+}
+
+// Generate sends the prompt to the chat completions endpoint
+func (p *OpenAIProvider) Generate(ctx context.Context, prompt string, opts Options) (string, error) {
+	base := p.Config.Endpoint
+	if base == "" {
+		base = defaultOpenAIEndpoint
+	}
+	endpoint := strings.TrimRight(base, "/") + "/chat/completions"
+
+	payload := OpenAIRequest{
+		Model:     p.Config.ModelID,
+		MaxTokens: maxTokens(opts, p.Config),
+		Messages:  messages(prompt, opts),
+	}
+	headers := map[string]string{}
+	if p.APIKey != "" {
+		headers["Authorization"] = "Bearer " + p.APIKey
+	}
+
+	var response OpenAIResponse
+	if err := postJSON(ctx, newCall(p.Name(), endpoint, headers, p.Config, p.progress()), payload, &response); err != nil {
+		return "", err
+	}
+
+	if len(response.Choices) > 0 {
+		p.progress().Report(Event{Kind: EventReceived, Provider: p.Name(), Time: time.Now()})
+		return response.Choices[0].Message.Content, nil
+	}
+
+	return "", fmt.Errorf("no content in response")
+}
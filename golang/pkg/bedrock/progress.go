@@ -0,0 +1,210 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/term"
+)
+
+// Supported values for the "progress" config key
+const (
+	ProgressAuto     = "auto"
+	ProgressSpinner  = "spinner"
+	ProgressSilent   = "silent"
+	ProgressJSONLine = "json"
+)
+
+// EventKind identifies a progress event
+type EventKind string
+
+// Progress events emitted during a model call
+const (
+	// EventWaiting is sent when a request goes out
+	EventWaiting EventKind = "waiting"
+	// EventResponding is sent when the response headers arrive
+	EventResponding EventKind = "responding"
+	// EventRetrying is sent before waiting to retry a failed request
+	EventRetrying EventKind = "retrying"
+	// EventReceived is sent when the complete response has been read
+	EventReceived EventKind = "received"
+	// EventFailed is sent when the call gives up
+	EventFailed EventKind = "failed"
+)
+
+// Event describes progress of a model call
+type Event struct {
+	Kind        EventKind `json:"event"`
+	Provider    string    `json:"provider"`
+	Time        time.Time `json:"time"`
+	Attempt     int       `json:"attempt,omitempty"`
+	MaxAttempts int       `json:"max_attempts,omitempty"`
+	WaitMs      int64     `json:"wait_ms,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	Streamed    bool      `json:"streamed,omitempty"`
+}
+
+// Reporter receives progress events from providers
+type Reporter interface {
+	Report(e Event)
+}
+
+// ReporterFunc adapts a function to the Reporter interface
+type ReporterFunc func(e Event)
+
+// Report calls f(e)
+func (f ReporterFunc) Report(e Event) {
+	f(e)
+}
+
+// NewReporter returns the reporter for a "progress" config value. "auto"
+// (or empty) shows a spinner when stdout is a terminal and stays silent
+// otherwise.
+func NewReporter(mode string) (Reporter, error) {
+	return NewReporterTo(mode, os.Stdout)
+}
+
+// NewReporterTo is NewReporter with the spinner drawn on out instead of
+// stdout, for callers whose stdout is not for people, such as git hooks
+func NewReporterTo(mode string, out *os.File) (Reporter, error) {
+	switch mode {
+	case "", ProgressAuto:
+		if term.IsTerminal(out) {
+			return NewTerminalReporter(out), nil
+		}
+		return SilentReporter{}, nil
+	case ProgressSpinner:
+		return NewTerminalReporter(out), nil
+	case ProgressSilent:
+		return SilentReporter{}, nil
+	case ProgressJSONLine:
+		return NewJSONReporter(os.Stderr), nil
+	default:
+		return nil, fmt.Errorf("unknown progress mode %q (expected %s, %s, %s or %s)",
+			mode, ProgressAuto, ProgressSpinner, ProgressSilent, ProgressJSONLine)
+	}
+}
+
+// reporting is embedded by providers to hold their progress reporter
+type reporting struct {
+	reporter Reporter
+}
+
+// SetReporter replaces the provider's progress reporter
+func (r *reporting) SetReporter(rep Reporter) {
+	r.reporter = rep
+}
+
+// progress returns the reporter, defaulting to a spinner on terminals only
+func (r *reporting) progress() Reporter {
+	if r.reporter == nil {
+		r.reporter, _ = NewReporter(ProgressAuto)
+	}
+	return r.reporter
+}
+
+// SilentReporter discards all events
+type SilentReporter struct{}
+
+// Report does nothing
+func (SilentReporter) Report(Event) {}
+
+// JSONReporter writes each event as one JSON object per line
+type JSONReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONReporter creates a JSON-lines reporter writing to w
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{enc: json.NewEncoder(w)}
+}
+
+// Report writes the event
+func (r *JSONReporter) Report(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(e)
+}
+
+// TerminalReporter animates a spinner with an elapsed timer while waiting
+type TerminalReporter struct {
+	out  io.Writer
+	mu   sync.Mutex
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewTerminalReporter creates a spinner reporter writing to out
+func NewTerminalReporter(out io.Writer) *TerminalReporter {
+	return &TerminalReporter{out: out}
+}
+
+// Report updates the terminal for the event
+func (r *TerminalReporter) Report(e Event) {
+	switch e.Kind {
+	case EventWaiting:
+		r.start(e.Provider)
+	case EventResponding:
+		r.stop()
+	case EventRetrying:
+		r.stop()
+		fmt.Fprintf(r.out, "⚠ :: %s request failed (%s), retrying in %.1fs [%d/%d]\n",
+			e.Provider, e.Reason, float64(e.WaitMs)/1000, e.Attempt, e.MaxAttempts)
+	case EventReceived:
+		r.stop()
+		if e.Streamed {
+			fmt.Fprintln(r.out)
+		}
+		fmt.Fprintf(r.out, "✔ :: Response received from %s\n", e.Provider)
+	case EventFailed:
+		r.stop()
+	}
+}
+
+// start animates the spinner until stop is called
+func (r *TerminalReporter) start(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done != nil {
+		return
+	}
+
+	spinnerChars := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	done := make(chan struct{})
+	r.done = done
+	startTime := time.Now()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			seconds := int(time.Since(startTime).Seconds())
+			fmt.Fprintf(r.out, "\r\033[K%s :: Awaiting response from %s ... [%ds]", spinnerChars[i%len(spinnerChars)], name, seconds)
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stop halts the spinner and clears its line
+func (r *TerminalReporter) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done == nil {
+		return
+	}
+	close(r.done)
+	r.wg.Wait()
+	r.done = nil
+	fmt.Fprint(r.out, "\r\033[K")
+}
@@ -0,0 +1,104 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReporterEvents(t *testing.T) {
+	stubSleep(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"content":[{"type":"text","text":"ok"}]}`))
+	}))
+	defer server.Close()
+
+	var kinds []EventKind
+	client := &Client{APIKey: "key", Config: Config{ModelID: "m", Endpoint: server.URL, TimeoutSeconds: 5, Retry: noJitterPolicy(2)}}
+	client.SetReporter(ReporterFunc(func(e Event) {
+		if e.Provider != "Bedrock" {
+			t.Errorf("Expected provider Bedrock, got %s", e.Provider)
+		}
+		kinds = append(kinds, e.Kind)
+	}))
+
+	if _, err := client.Generate(context.Background(), "prompt", Options{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []EventKind{EventWaiting, EventResponding, EventRetrying, EventWaiting, EventResponding, EventReceived}
+	if len(kinds) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, kinds)
+	}
+	for i := range expected {
+		if kinds[i] != expected[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, expected[i], kinds[i])
+		}
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJSONReporter(&buf)
+	r.Report(Event{Kind: EventWaiting, Provider: "Bedrock"})
+	r.Report(Event{Kind: EventRetrying, Provider: "Bedrock", Attempt: 2, MaxAttempts: 4, WaitMs: 1500})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var e Event
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatalf("Failed to decode line: %v", err)
+	}
+	if e.Kind != EventRetrying || e.Attempt != 2 || e.WaitMs != 1500 {
+		t.Errorf("Unexpected event %+v", e)
+	}
+	if strings.Contains(buf.String(), "\033") {
+		t.Errorf("JSON output should not contain escape codes")
+	}
+}
+
+func TestTerminalReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewTerminalReporter(&buf)
+	r.Report(Event{Kind: EventWaiting, Provider: "Bedrock"})
+	r.Report(Event{Kind: EventResponding, Provider: "Bedrock"})
+	r.Report(Event{Kind: EventReceived, Provider: "Bedrock"})
+
+	out := buf.String()
+	if !strings.Contains(out, "Awaiting response from Bedrock") {
+		t.Errorf("Expected spinner output, got %q", out)
+	}
+	if !strings.HasSuffix(out, "\r\033[K✔ :: Response received from Bedrock\n") {
+		t.Errorf("Expected spinner to be cleared before completion, got %q", out)
+	}
+}
+
+func TestNewReporter(t *testing.T) {
+	for _, mode := range []string{"", ProgressAuto, ProgressSpinner, ProgressSilent, ProgressJSONLine} {
+		if _, err := NewReporter(mode); err != nil {
+			t.Errorf("Unexpected error for %q: %v", mode, err)
+		}
+	}
+	if _, err := NewReporter("fancy"); err == nil {
+		t.Errorf("Expected error for unknown mode")
+	}
+
+	// Tests do not run on a terminal, so auto must stay silent
+	r, _ := NewReporter(ProgressAuto)
+	if _, ok := r.(SilentReporter); !ok {
+		t.Errorf("Expected silent reporter when stdout is not a terminal, got %T", r)
+	}
+}
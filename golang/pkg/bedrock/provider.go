@@ -0,0 +1,291 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Supported provider names for the "provider" config key
+const (
+	ProviderBedrock   = "bedrock"
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+	ProviderOllama    = "ollama"
+)
+
+// Provider generates text from a prompt using a specific model backend
+type Provider interface {
+	// Name returns a human readable name for the backend (e.g. "Bedrock")
+	Name() string
+	// Generate sends the prompt to the model and returns its text response
+	Generate(ctx context.Context, prompt string, opts Options) (string, error)
+	// SetReporter replaces the reporter that receives progress events
+	SetReporter(r Reporter)
+}
+
+// Streamer is implemented by providers that can deliver text incrementally.
+// StreamModel calls onDelta for each text fragment and returns the full text.
+type Streamer interface {
+	StreamModel(ctx context.Context, prompt string, opts Options, onDelta func(string)) (string, error)
+}
+
+// Options tunes a single generation request
+type Options struct {
+	// MaxTokens caps the response length; zero uses the configured default
+	MaxTokens int
+	// History holds earlier turns of the conversation, oldest first, sent
+	// before the prompt so follow-up requests build on previous answers
+	History []Message
+}
+
+// NewProvider returns the Provider selected by cfg.Provider
+func NewProvider(cfg Config) (Provider, error) {
+	reporter, err := NewReporter(cfg.Progress)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Provider
+	switch strings.ToLower(cfg.Provider) {
+	case "", ProviderBedrock:
+		p, err = newBedrockClient(cfg)
+	case ProviderAnthropic:
+		p, err = newAnthropicProvider(cfg)
+	case ProviderOpenAI:
+		p, err = newOpenAIProvider(cfg)
+	case ProviderOllama:
+		p = newOllamaProvider(cfg)
+	default:
+		return nil, fmt.Errorf("unknown provider %q (expected one of: %s, %s, %s, %s)",
+			cfg.Provider, ProviderBedrock, ProviderAnthropic, ProviderOpenAI, ProviderOllama)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.SetReporter(reporter)
+	return p, nil
+}
+
+// maxTokens returns the requested token limit, falling back to the configured one
+func maxTokens(opts Options, cfg Config) int {
+	if opts.MaxTokens > 0 {
+		return opts.MaxTokens
+	}
+	if cfg.MaxTokens > 0 {
+		return cfg.MaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// messages returns the conversation for a request: the history followed by
+// the prompt as the newest user turn
+func messages(prompt string, opts Options) []Message {
+	out := append([]Message(nil), opts.History...)
+	return append(out, Message{Role: "user", Content: prompt})
+}
+
+// keyEnvs names the variable each provider reads its API key from when
+// api_key_env is not set
+var keyEnvs = map[string]string{
+	ProviderBedrock:   "GUD_BEDROCK_API_KEY",
+	ProviderAnthropic: "ANTHROPIC_API_KEY",
+	ProviderOpenAI:    "OPENAI_API_KEY",
+}
+
+// apiKeyEnv returns the variable provider reads its API key from
+func apiKeyEnv(cfg Config, provider string) string {
+	if cfg.APIKeyEnv != "" {
+		return cfg.APIKeyEnv
+	}
+	return keyEnvs[strings.ToLower(provider)]
+}
+
+// apiKeyFromEnv reads the API key for provider and returns it with the name
+// of its variable
+func apiKeyFromEnv(cfg Config, provider string) (string, string) {
+	name := apiKeyEnv(cfg, provider)
+	return os.Getenv(name), name
+}
+
+// signFunc adds authentication to a request once its body is known
+type signFunc func(req *http.Request, body []byte) error
+
+// apiCall describes an HTTP call to a provider endpoint
+type apiCall struct {
+	name     string
+	endpoint string
+	headers  map[string]string
+	sign     signFunc
+	// keyEnv names the variable the API key came from, for error hints
+	keyEnv   string
+	timeout  int
+	retry    RetryPolicy
+	reporter Reporter
+}
+
+// newCall describes an unsigned call using the API key variable, timeout and
+// retry policy from cfg
+func newCall(name, endpoint string, headers map[string]string, cfg Config, reporter Reporter) apiCall {
+	return apiCall{name: name, endpoint: endpoint, headers: headers, keyEnv: apiKeyEnv(cfg, name),
+		timeout: cfg.TimeoutSeconds, retry: cfg.Retry, reporter: reporter}
+}
+
+// postJSON sends payload to the endpoint and decodes the JSON response into out
+func postJSON(ctx context.Context, call apiCall, payload, out interface{}) error {
+	client := &http.Client{Timeout: time.Duration(call.timeout) * time.Second}
+	resp, err := doRequest(ctx, client, call, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// doRequest posts payload as JSON and returns the response once its headers
+// arrive. Throttling, transient server errors and network failures are
+// retried according to the call's policy; other failures return an *APIError.
+func doRequest(ctx context.Context, client *http.Client, call apiCall, payload interface{}) (*http.Response, error) {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	attempts := call.retry.attempts()
+	for attempt := 1; ; attempt++ {
+		resp, err := send(ctx, client, call, jsonPayload)
+		if err == nil {
+			return resp, nil
+		}
+
+		if ctx.Err() != nil {
+			call.report(Event{Kind: EventFailed, Reason: ctx.Err().Error()})
+			return nil, ctx.Err()
+		}
+
+		if !retryable(err) || attempt >= attempts {
+			call.report(Event{Kind: EventFailed, Reason: err.Error()})
+			return nil, err
+		}
+
+		var retryAfter time.Duration
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			retryAfter = apiErr.RetryAfter
+		}
+		wait := call.retry.delay(attempt, retryAfter)
+		call.report(Event{
+			Kind:        EventRetrying,
+			Attempt:     attempt + 1,
+			MaxAttempts: attempts,
+			WaitMs:      wait.Milliseconds(),
+			Reason:      retryReason(err),
+		})
+		if err := sleep(ctx, wait); err != nil {
+			call.report(Event{Kind: EventFailed, Reason: err.Error()})
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether a failed attempt may succeed if sent again:
+// network failures and API errors such as throttling are, while errors
+// building or signing the request are not
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// send makes a single attempt at the call
+func send(ctx context.Context, client *http.Client, call apiCall, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", call.endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range call.headers {
+		req.Header.Set(k, v)
+	}
+	if call.sign != nil {
+		if err := call.sign(req, body); err != nil {
+			return nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+	}
+
+	call.report(Event{Kind: EventWaiting})
+	resp, err := client.Do(req)
+	call.report(Event{Kind: EventResponding})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		apiErr := newAPIError(call.name, resp, respBody)
+		apiErr.keyEnv = call.keyEnv
+		return nil, apiErr
+	}
+	return resp, nil
+}
+
+// report sends an event for this call to its reporter, if any
+func (call apiCall) report(e Event) {
+	if call.reporter == nil {
+		return
+	}
+	e.Provider = call.name
+	e.Time = time.Now()
+	call.reporter.Report(e)
+}
+
+// retryReason summarises a retryable failure for the retry notice
+func retryReason(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.Type != "" {
+			return apiErr.Type
+		}
+		return fmt.Sprintf("HTTP %d", apiErr.StatusCode)
+	}
+	return "network error"
+}
+
+// streamingClient bounds the wait for response headers only, so long
+// generations are not cut off while tokens are still arriving.
+func streamingClient(timeoutSeconds int) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = time.Duration(timeoutSeconds) * time.Second
+	return &http.Client{Transport: transport}
+}
+
+// GenerateStream streams the response through onDelta when the provider
+// supports it, and otherwise delivers the complete response as one delta.
+func GenerateStream(ctx context.Context, p Provider, prompt string, opts Options, onDelta func(string)) (string, error) {
+	if s, ok := p.(Streamer); ok {
+		return s.StreamModel(ctx, prompt, opts, onDelta)
+	}
+	text, err := p.Generate(ctx, prompt, opts)
+	if err == nil && onDelta != nil {
+		onDelta(text)
+	}
+	return text, err
+}
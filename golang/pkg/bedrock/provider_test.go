@@ -0,0 +1,193 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewProvider(t *testing.T) {
+	t.Setenv("GUD_BEDROCK_API_KEY", "bedrock-key")
+	t.Setenv("ANTHROPIC_API_KEY", "anthropic-key")
+	t.Setenv("OPENAI_API_KEY", "openai-key")
+
+	tests := []struct {
+		provider string
+		expected string
+		hasError bool
+	}{
+		{provider: "", expected: "Bedrock"},
+		{provider: ProviderBedrock, expected: "Bedrock"},
+		{provider: ProviderAnthropic, expected: "Anthropic"},
+		{provider: ProviderOpenAI, expected: "OpenAI"},
+		{provider: ProviderOllama, expected: "Ollama"},
+		{provider: "unknown", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			p, err := NewProvider(Config{Provider: tt.provider, ModelID: "model"})
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if p.Name() != tt.expected {
+				t.Errorf("Expected provider %s, got %s", tt.expected, p.Name())
+			}
+		})
+	}
+}
+
+func TestNewProviderMissingKey(t *testing.T) {
+	t.Setenv("GUD_BEDROCK_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	for _, name := range []string{ProviderBedrock, ProviderAnthropic, ProviderOpenAI} {
+		if _, err := NewProvider(Config{Provider: name}); err == nil {
+			t.Errorf("Expected error for %s without API key", name)
+		}
+	}
+
+	// OpenAI-compatible servers with a custom endpoint may not need a key
+	if _, err := NewProvider(Config{Provider: ProviderOpenAI, Endpoint: "http://localhost:8080/v1"}); err != nil {
+		t.Errorf("Unexpected error for keyless OpenAI-compatible endpoint: %v", err)
+	}
+}
+
+func TestProviderGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		response interface{}
+		newFunc  func(cfg Config) Provider
+	}{
+		{
+			name: "Bedrock",
+			path: "/model/test-model/invoke",
+			response: BedrockResponse{
+				Content: []ContentBlock{{Type: "text", Text: "generated"}},
+			},
+			newFunc: func(cfg Config) Provider { return &Client{APIKey: "key", Config: cfg} },
+		},
+		{
+			name: "Anthropic",
+			path: "/v1/messages",
+			response: BedrockResponse{
+				Content: []ContentBlock{{Type: "text", Text: "generated"}},
+			},
+			newFunc: func(cfg Config) Provider { return &AnthropicProvider{APIKey: "key", Config: cfg} },
+		},
+		{
+			name: "OpenAI",
+			path: "/chat/completions",
+			response: OpenAIResponse{
+				Choices: []OpenAIChoice{{Message: Message{Role: "assistant", Content: "generated"}}},
+			},
+			newFunc: func(cfg Config) Provider { return &OpenAIProvider{APIKey: "key", Config: cfg} },
+		},
+		{
+			name: "Ollama",
+			path: "/api/chat",
+			response: OllamaResponse{
+				Message: Message{Role: "assistant", Content: "generated"},
+				Done:    true,
+			},
+			newFunc: func(cfg Config) Provider { return &OllamaProvider{Config: cfg} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					t.Errorf("Expected path %s, got %s", tt.path, r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("Failed to decode request: %v", err)
+				}
+				json.NewEncoder(w).Encode(tt.response)
+			}))
+			defer server.Close()
+
+			cfg := Config{ModelID: "test-model", Endpoint: server.URL, TimeoutSeconds: 5, MaxTokens: 100}
+			history := []Message{{Role: "user", Content: "first"}, {Role: "assistant", Content: "answer"}}
+			result, err := tt.newFunc(cfg).Generate(context.Background(), "prompt", Options{History: history})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != "generated" {
+				t.Errorf("Expected 'generated', got %q", result)
+			}
+			messages, ok := body["messages"].([]interface{})
+			if !ok || len(messages) != 3 {
+				t.Fatalf("Expected the history and prompt in request body, got %v", body)
+			}
+			if last := messages[2].(map[string]interface{}); last["role"] != "user" || last["content"] != "prompt" {
+				t.Errorf("Expected the prompt as the last message, got %v", last)
+			}
+		})
+	}
+}
+
+func TestProviderGenerateError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"bad request"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	p := &OllamaProvider{Config: Config{ModelID: "test-model", Endpoint: server.URL, TimeoutSeconds: 5}}
+	if _, err := p.Generate(context.Background(), "prompt", Options{}); err == nil {
+		t.Errorf("Expected error for non-200 response")
+	}
+}
+
+func TestMaxTokens(t *testing.T) {
+	if n := maxTokens(Options{MaxTokens: 10}, Config{MaxTokens: 20}); n != 10 {
+		t.Errorf("Expected request override 10, got %d", n)
+	}
+	if n := maxTokens(Options{}, Config{MaxTokens: 20}); n != 20 {
+		t.Errorf("Expected configured 20, got %d", n)
+	}
+	if n := maxTokens(Options{}, Config{}); n != defaultMaxTokens {
+		t.Errorf("Expected default %d, got %d", defaultMaxTokens, n)
+	}
+}
+
+func TestProviderGenerateCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	p := &OllamaProvider{Config: Config{ModelID: "test-model", Endpoint: server.URL, TimeoutSeconds: 30, Retry: DefaultRetryPolicy()}}
+	start := time.Now()
+	_, err := p.Generate(ctx, "prompt", Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected cancellation to return promptly, took %v", elapsed)
+	}
+}
@@ -0,0 +1,71 @@
+package bedrock
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first
+	MaxAttempts int `json:"max_attempts"`
+	// BaseDelayMs is the delay before the first retry; it doubles each attempt
+	BaseDelayMs int `json:"base_delay_ms"`
+	// MaxDelayMs caps the computed backoff delay
+	MaxDelayMs int `json:"max_delay_ms"`
+	// Jitter randomises each delay between half and the full value
+	Jitter *bool `json:"jitter"`
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	jitter := true
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelayMs: 1000,
+		MaxDelayMs:  20000,
+		Jitter:      &jitter,
+	}
+}
+
+// attempts returns the number of tries, never less than one
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// delay returns how long to wait before retry number attempt (starting at 1),
+// never less than the server's Retry-After hint.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	d := time.Duration(p.BaseDelayMs) * time.Millisecond
+	maxDelay := time.Duration(p.MaxDelayMs) * time.Millisecond
+	for i := 1; i < attempt && (maxDelay <= 0 || d < maxDelay); i++ {
+		d *= 2
+	}
+	if maxDelay > 0 && d > maxDelay {
+		d = maxDelay
+	}
+	if p.Jitter != nil && *p.Jitter && d > 0 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	if retryAfter > d {
+		d = retryAfter
+	}
+	return d
+}
+
+// sleep waits for d or until ctx is done; it is replaced in tests to avoid
+// real waits.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,241 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func noJitterPolicy(attempts int) RetryPolicy {
+	jitter := false
+	return RetryPolicy{MaxAttempts: attempts, BaseDelayMs: 100, MaxDelayMs: 1000, Jitter: &jitter}
+}
+
+func stubSleep(t *testing.T) *[]time.Duration {
+	var waits []time.Duration
+	original := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	t.Cleanup(func() { sleep = original })
+	return &waits
+}
+
+func TestRetryOnThrottling(t *testing.T) {
+	waits := stubSleep(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("X-Amzn-ErrorType", "ThrottlingException:http://internal.amazon.com/coral/com.amazon.bedrock/")
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message":"Too many requests, please wait before trying again."}`))
+			return
+		}
+		w.Write([]byte(`{"content":[{"type":"text","text":"ok"}]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{APIKey: "key", Config: Config{ModelID: "m", Endpoint: server.URL, TimeoutSeconds: 5, Retry: noJitterPolicy(4)}}
+	result, err := client.Generate(context.Background(), "prompt", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "ok" || calls != 3 {
+		t.Errorf("Expected success after 3 calls, got %q after %d", result, calls)
+	}
+	// Retry-After (2s) outweighs the computed backoff
+	if len(*waits) != 2 || (*waits)[0] != 2*time.Second {
+		t.Errorf("Expected two waits honouring Retry-After, got %v", *waits)
+	}
+}
+
+func TestNoRetryOnAuthFailure(t *testing.T) {
+	stubSleep(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"The security token included in the request is expired"}`))
+	}))
+	defer server.Close()
+
+	client := &Client{APIKey: "key", Config: Config{ModelID: "m", Endpoint: server.URL, TimeoutSeconds: 5, Retry: noJitterPolicy(4)}}
+	_, err := client.Generate(context.Background(), "prompt", Options{})
+	if !errors.Is(err, ErrAuth) {
+		t.Errorf("Expected ErrAuth, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single call, got %d", calls)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "The security token included in the request is expired" {
+		t.Errorf("Expected APIError with message, got %#v", err)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	waits := stubSleep(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"message":"Service unavailable"}`))
+	}))
+	defer server.Close()
+
+	p := &OllamaProvider{Config: Config{ModelID: "m", Endpoint: server.URL, TimeoutSeconds: 5, Retry: noJitterPolicy(3)}}
+	_, err := p.Generate(context.Background(), "prompt", Options{})
+	if !errors.Is(err, ErrServer) {
+		t.Errorf("Expected ErrServer, got %v", err)
+	}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if len(*waits) != len(expected) || (*waits)[0] != expected[0] || (*waits)[1] != expected[1] {
+		t.Errorf("Expected waits %v, got %v", expected, *waits)
+	}
+}
+
+func TestRetryOnNetworkError(t *testing.T) {
+	waits := stubSleep(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			// Drop the connection without a response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{"message":{"content":"ok"}}`))
+	}))
+	defer server.Close()
+
+	p := &OllamaProvider{Config: Config{ModelID: "m", Endpoint: server.URL, TimeoutSeconds: 5, Retry: noJitterPolicy(3)}}
+	result, err := p.Generate(context.Background(), "prompt", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "ok" || calls != 2 || len(*waits) != 1 {
+		t.Errorf("Expected success after one retry, got %q after %d calls and %d waits", result, calls, len(*waits))
+	}
+}
+
+func TestNoRetryOnRequestError(t *testing.T) {
+	waits := stubSleep(t)
+	calls := 0
+	call := apiCall{
+		name:     "Test",
+		endpoint: "http://127.0.0.1:1",
+		sign: func(*http.Request, []byte) error {
+			calls++
+			return errors.New("no credentials")
+		},
+		retry:    noJitterPolicy(4),
+		reporter: SilentReporter{},
+	}
+	if _, err := doRequest(context.Background(), http.DefaultClient, call, struct{}{}); err == nil {
+		t.Fatal("Expected the signing error")
+	}
+	if calls != 1 || len(*waits) != 0 {
+		t.Errorf("Expected a single attempt, got %d attempts and %d waits", calls, len(*waits))
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := noJitterPolicy(10)
+	tests := []struct {
+		attempt    int
+		retryAfter time.Duration
+		expected   time.Duration
+	}{
+		{attempt: 1, expected: 100 * time.Millisecond},
+		{attempt: 3, expected: 400 * time.Millisecond},
+		{attempt: 8, expected: time.Second},
+		{attempt: 1, retryAfter: 5 * time.Second, expected: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.delay(tt.attempt, tt.retryAfter); got != tt.expected {
+			t.Errorf("delay(%d, %v): expected %v, got %v", tt.attempt, tt.retryAfter, tt.expected, got)
+		}
+	}
+
+	jittered := DefaultRetryPolicy()
+	for i := 0; i < 20; i++ {
+		d := jittered.delay(1, 0)
+		if d < 500*time.Millisecond || d > time.Second {
+			t.Errorf("Jittered delay %v outside [500ms, 1s]", d)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+	}{
+		{name: "Bedrock throttling", status: 429, body: `{"message":"Too many requests"}`, expected: ErrThrottled},
+		{name: "Bedrock model not ready", status: 429, body: `{"__type":"ModelNotReadyException","message":"Model is not ready"}`, expected: ErrModelNotReady},
+		{name: "Bedrock validation", status: 400, body: `{"message":"Input is too long for requested model."}`, expected: ErrValidation},
+		{name: "Bedrock model not found", status: 404, body: `{"message":"Could not resolve the foundation model"}`, expected: ErrModelNotFound},
+		{name: "Anthropic auth", status: 401, body: `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, expected: ErrAuth},
+		{name: "Anthropic overloaded", status: 529, body: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, expected: ErrServer},
+		{name: "OpenAI rate limit", status: 429, body: `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`, expected: ErrThrottled},
+		{name: "Ollama missing model", status: 404, body: `{"error":"model \"llama3\" not found, try pulling it first"}`, expected: ErrModelNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			err := newAPIError("Test", resp, []byte(tt.body))
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+			if err.Message == "" {
+				t.Errorf("Expected message to be extracted from %s", tt.body)
+			}
+		})
+	}
+}
+
+func TestAuthHint(t *testing.T) {
+	tests := []struct {
+		name, provider, keyEnv, expected string
+	}{
+		{name: "Bedrock key", provider: "Bedrock", keyEnv: "GUD_BEDROCK_API_KEY", expected: "check GUD_BEDROCK_API_KEY (short-term keys expire after 12 hours) or your AWS credentials"},
+		{name: "Bedrock custom key", provider: "Bedrock", keyEnv: "WORK_BEDROCK_KEY", expected: "check WORK_BEDROCK_KEY ("},
+		{name: "Bedrock SigV4", provider: "Bedrock", expected: "(check your AWS credentials)"},
+		{name: "Anthropic key", provider: "Anthropic", keyEnv: "MY_ANTHROPIC_KEY", expected: "(check MY_ANTHROPIC_KEY)"},
+		{name: "No key", provider: "OpenAI", expected: "(check your API key)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: http.StatusUnauthorized, Header: http.Header{}}
+			err := newAPIError(tt.provider, resp, []byte(`{"message":"denied"}`))
+			err.keyEnv = tt.keyEnv
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected %q in %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestAuthHintFromConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer server.Close()
+
+	p := &AnthropicProvider{APIKey: "key", Config: Config{ModelID: "m", Endpoint: server.URL, APIKeyEnv: "WORK_ANTHROPIC_KEY", TimeoutSeconds: 5}}
+	_, err := p.Generate(context.Background(), "prompt", Options{})
+	if err == nil || !strings.Contains(err.Error(), "check WORK_ANTHROPIC_KEY") {
+		t.Errorf("Expected the hint to name the configured variable, got %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package changelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// StandardSections are the Keep a Changelog categories, in the order they
+// are written
+var StandardSections = []Section{
+	{Name: "Added", Description: "New features"},
+	{Name: "Changed", Description: "Changes to existing functionality"},
+	{Name: "Deprecated", Description: "Features that will be removed in a future release"},
+	{Name: "Removed", Description: "Features removed in this release"},
+	{Name: "Fixed", Description: "Bug fixes"},
+	{Name: "Security", Description: "Fixed vulnerabilities and other security improvements"},
+}
+
+// Section is a category of changelog entries
+type Section struct {
+	// Name is the section heading, e.g. "Performance"
+	Name string `json:"name"`
+	// Description tells the model what belongs in the section
+	Description string `json:"description"`
+	// Types lists conventional commit types whose commits go in the section
+	// when entries are built from the commit log
+	Types []string `json:"types"`
+}
+
+// Options configures the changelog sections. It is the single source for
+// the JSON schema sent to the model, the prompt and the section order.
+type Options struct {
+	// Sections lists extra sections, written after the standard ones
+	Sections []Section `json:"sections"`
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{}
+}
+
+// keyPattern restricts section names to what can be a JSON key
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Key returns the JSON key the model uses for a section, e.g. "added" or
+// "breaking_changes"
+func Key(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), "_")
+}
+
+// Validate reports custom sections without a usable name, and names used
+// twice or by a standard section
+func (o Options) Validate() error {
+	seen := map[string]bool{}
+	for _, s := range StandardSections {
+		seen[Key(s.Name)] = true
+	}
+	for _, s := range o.Sections {
+		key := Key(s.Name)
+		if !keyPattern.MatchString(key) {
+			return fmt.Errorf("invalid changelog section name %q: use letters, digits and spaces, starting with a letter", s.Name)
+		}
+		if seen[key] {
+			return fmt.Errorf("changelog section %q is defined more than once", s.Name)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// All returns the standard sections followed by the custom ones
+func (o Options) All() []Section {
+	return append(append([]Section(nil), StandardSections...), o.Sections...)
+}
+
+// Lookup returns the section whose name or key matches name in any case
+func (o Options) Lookup(name string) (Section, bool) {
+	key := Key(name)
+	for _, s := range o.All() {
+		if Key(s.Name) == key {
+			return s, true
+		}
+	}
+	return Section{}, false
+}
+
+// SectionForType returns the custom section that collects commits of type
+// typ, if one is configured
+func (o Options) SectionForType(typ string) (string, bool) {
+	for _, s := range o.Sections {
+		for _, t := range s.Types {
+			if strings.EqualFold(t, typ) {
+				return s.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Schema returns the JSON schema the model must follow for changelog entries
+func (o Options) Schema() string {
+	var props, required []string
+	for _, s := range o.All() {
+		key := Key(s.Name)
+		props = append(props, fmt.Sprintf(`        %s: {
+          "type": "array",
+          "items": {"type": "string"},
+          "description": %s
+        }`, jsonString(key), jsonString(s.Description)))
+		required = append(required, jsonString(key))
+	}
+	return `{
+  "type": "object",
+  "properties": {
+    "changelog": {
+      "type": "object",
+      "properties": {
+` + strings.Join(props, ",\n") + `
+      },
+      "required": [` + strings.Join(required, ", ") + `],
+      "additionalProperties": false
+    }
+  },
+  "required": ["changelog"],
+  "additionalProperties": false
+}`
+}
+
+// PromptRules returns the prompt lines describing the sections
+func (o Options) PromptRules() string {
+	names := make([]string, 0, len(o.All()))
+	for _, s := range o.All() {
+		names = append(names, s.Name)
+	}
+	lines := []string{
+		"- Sort every entry into exactly one section: " + strings.Join(names, ", "),
+		"- Bug fixes go in fixed and vulnerability fixes in security, not in changed",
+	}
+	for _, s := range o.Sections {
+		if s.Description != "" {
+			lines = append(lines, fmt.Sprintf("- %s: %s", Key(s.Name), s.Description))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Entry holds the items of a changelog release by section name
+type Entry map[string][]string
+
+// Add appends item to section
+func (e Entry) Add(section, item string) {
+	e[section] = append(e[section], item)
+}
+
+// Empty reports whether the entry has nothing to list
+func (e Entry) Empty() bool {
+	for _, items := range e {
+		if len(items) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Ordered returns the sections of e that have items: the standard and
+// custom sections in order, then any others alphabetically
+func (o Options) Ordered(e Entry) []string {
+	var names []string
+	known := map[string]bool{}
+	for _, s := range o.All() {
+		known[s.Name] = true
+		if len(e[s.Name]) > 0 {
+			names = append(names, s.Name)
+		}
+	}
+	var others []string
+	for name, items := range e {
+		if !known[name] && len(items) > 0 {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// response is the JSON the model returns
+type response struct {
+	Changelog map[string][]string `json:"changelog"`
+}
+
+// Parse reads the model's JSON response into an entry. Keys are matched to
+// the sections in any case; items under keys that are not sections are
+// listed under Changed.
+func (o Options) Parse(text string) (Entry, error) {
+	// Clean the response - remove any markdown formatting or extra text
+	cleaned := regexp.MustCompile("```(json)?\n?").ReplaceAllString(text, "")
+	start, end := strings.Index(cleaned, "{"), strings.LastIndex(cleaned, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("failed to parse changelog response: no JSON object")
+	}
+
+	var resp response
+	if err := json.Unmarshal([]byte(cleaned[start:end+1]), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse changelog response: %w", err)
+	}
+
+	// Walk the keys in order so unknown sections are merged deterministically
+	keys := make([]string, 0, len(resp.Changelog))
+	for key := range resp.Changelog {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entry := Entry{}
+	add := func(name string, items []string) {
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				entry.Add(name, item)
+			}
+		}
+	}
+	var unknown []string
+	for _, key := range keys {
+		if s, ok := o.Lookup(key); ok {
+			add(s.Name, resp.Changelog[key])
+		} else {
+			unknown = append(unknown, key)
+		}
+	}
+	for _, key := range unknown {
+		add("Changed", resp.Changelog[key])
+	}
+	return entry, nil
+}
+
+// MarshalEntry returns e as the JSON the schema describes
+func (o Options) MarshalEntry(e Entry) ([]byte, error) {
+	out := map[string][]string{}
+	for _, s := range o.All() {
+		out[Key(s.Name)] = append([]string{}, e[s.Name]...)
+	}
+	for name, items := range e {
+		if _, ok := o.Lookup(name); !ok {
+			out[Key(name)] = append(out[Key(name)], items...)
+		}
+	}
+	return json.MarshalIndent(response{Changelog: out}, "", "  ")
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,100 @@
+package changelog
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []Section
+		valid    bool
+	}{
+		{name: "None", valid: true},
+		{name: "Custom", sections: []Section{{Name: "Performance"}, {Name: "Breaking Changes"}}, valid: true},
+		{name: "Standard name", sections: []Section{{Name: "security"}}},
+		{name: "Twice", sections: []Section{{Name: "Docs"}, {Name: "docs"}}},
+		{name: "Empty name", sections: []Section{{Name: " "}}},
+		{name: "Punctuation", sections: []Section{{Name: "Perf!"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (Options{Sections: tt.sections}).Validate(); (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	opts := Options{Sections: []Section{{Name: "Breaking Changes", Description: "What breaks"}}}
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(opts.Schema()), &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v\n%s", err, opts.Schema())
+	}
+	changelog := schema["properties"].(map[string]interface{})["changelog"].(map[string]interface{})
+	required := changelog["required"].([]interface{})
+	var keys []string
+	for _, k := range required {
+		keys = append(keys, k.(string))
+	}
+	if strings.Join(keys, ",") != "added,changed,deprecated,removed,fixed,security,breaking_changes" {
+		t.Errorf("Unexpected required keys %v", keys)
+	}
+
+	rules := opts.PromptRules()
+	if !strings.Contains(rules, "Added, Changed, Deprecated, Removed, Fixed, Security, Breaking Changes") || !strings.Contains(rules, "- breaking_changes: What breaks") {
+		t.Errorf("Unexpected prompt rules:\n%s", rules)
+	}
+}
+
+func TestParse(t *testing.T) {
+	opts := Options{Sections: []Section{{Name: "Performance", Types: []string{"perf"}}}}
+	entry, err := opts.Parse("```json\n" + `{"changelog": {"Fixed": ["Handle empty bodies", " "], "performance": ["Batch inserts"], "other": ["Tidy up"], "changed": ["Faster startup"]}}` + "\n```")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := Entry{
+		"Changed":     {"Faster startup", "Tidy up"},
+		"Fixed":       {"Handle empty bodies"},
+		"Performance": {"Batch inserts"},
+	}
+	if !reflect.DeepEqual(entry, expected) {
+		t.Errorf("Expected %v, got %v", expected, entry)
+	}
+	if got := opts.Ordered(entry); !reflect.DeepEqual(got, []string{"Changed", "Fixed", "Performance"}) {
+		t.Errorf("Unexpected order %v", got)
+	}
+
+	if name, ok := opts.SectionForType("PERF"); !ok || name != "Performance" {
+		t.Errorf("Expected the custom section for perf, got %q", name)
+	}
+
+	for _, bad := range []string{"", "no json", `{"changelog": {"added": "not a list"}}`} {
+		if _, err := opts.Parse(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestMarshalEntry(t *testing.T) {
+	opts := Options{Sections: []Section{{Name: "Performance"}}}
+	data, err := opts.MarshalEntry(Entry{"Added": {"Retries"}, "Performance": {"Batch inserts"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What is written can be read back
+	entry, err := opts.Parse(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entry, Entry{"Added": {"Retries"}, "Performance": {"Batch inserts"}}) {
+		t.Errorf("Unexpected round trip %v from:\n%s", entry, data)
+	}
+	if !strings.Contains(string(data), `"security": []`) {
+		t.Errorf("Expected every section in the JSON:\n%s", data)
+	}
+}
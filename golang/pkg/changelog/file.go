@@ -0,0 +1,391 @@
+package changelog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Header starts a new changelog file
+const Header = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+
+`
+
+// Unreleased is the version of the section collecting changes that are not
+// released yet
+const Unreleased = "Unreleased"
+
+var (
+	// linkPattern matches a link reference definition such as
+	// "[1.2.0]: https://github.com/o/r/compare/v1.1.0...v1.2.0"
+	linkPattern = regexp.MustCompile(`^\[([^\]]+)\]:\s*(\S+)`)
+	// itemPattern matches the first line of a list item
+	itemPattern = regexp.MustCompile(`^\s*[-*+]\s+`)
+)
+
+// File is a CHANGELOG.md in Keep a Changelog form: a preamble, release
+// sections and link references at the end. It keeps the text of every part
+// as written, so String returns the parsed text unchanged until the file is
+// modified, and only the modified parts afterwards.
+type File struct {
+	// Preamble is everything before the first release, such as the title
+	Preamble string
+	// Releases are the "## " sections, newest first
+	Releases []*Release
+	// Links holds the link reference definitions at the end of the file
+	Links string
+}
+
+// Release is a "## " section of the changelog
+type Release struct {
+	// Heading is the heading without "## ", e.g. "[1.2.0] - 2024-05-01"
+	Heading string
+	// Body is everything after the heading line up to the next release
+	Body string
+
+	// line is the heading line as written, kept while Heading is unchanged
+	line string
+}
+
+// ParseFile splits a changelog into its parts. Any text is accepted; what
+// is not a release or a trailing link reference is part of the preamble or
+// of the release before it.
+func ParseFile(text string) *File {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	// The link references are the trailing run of definitions and blank lines
+	end := len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if linkPattern.MatchString(lines[i]) {
+			end = i
+		} else if strings.TrimSpace(lines[i]) != "" {
+			break
+		}
+	}
+
+	f := &File{Links: strings.Join(lines[end:], "")}
+	var part strings.Builder
+	var current *Release
+	fenced := false
+	for _, line := range lines[:end] {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if !fenced && strings.HasPrefix(line, "## ") {
+			if current == nil {
+				f.Preamble = part.String()
+			} else {
+				current.Body = part.String()
+			}
+			part.Reset()
+			current = &Release{Heading: strings.TrimSpace(line[3:]), line: line}
+			f.Releases = append(f.Releases, current)
+			continue
+		}
+		part.WriteString(line)
+	}
+	if current == nil {
+		f.Preamble = part.String()
+	} else {
+		current.Body = part.String()
+	}
+	return f
+}
+
+// String returns the changelog as text
+func (f *File) String() string {
+	var b strings.Builder
+	b.WriteString(f.Preamble)
+	for _, r := range f.Releases {
+		b.WriteString(r.String())
+	}
+	b.WriteString(f.Links)
+	return b.String()
+}
+
+// String returns the release section as text
+func (r *Release) String() string {
+	line := r.line
+	if strings.TrimSpace(line) != "## "+r.Heading {
+		line = "## " + r.Heading + "\n"
+	}
+	return line + r.Body
+}
+
+// Version returns the version the release is for, e.g. "1.2.0" for
+// "[1.2.0] - 2024-05-01", or "Unreleased"
+func (r *Release) Version() string {
+	heading := r.Heading
+	if strings.HasPrefix(heading, "[") {
+		if end := strings.Index(heading, "]"); end > 0 {
+			return strings.TrimSpace(heading[1:end])
+		}
+	}
+	if fields := strings.Fields(heading); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// Release returns the first release for version, in any case, or nil
+func (f *File) Release(version string) *Release {
+	for _, r := range f.Releases {
+		if strings.EqualFold(r.Version(), version) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Merge adds the releases in update to the changelog. Entries for a release
+// the changelog already has, such as Unreleased, are added to its sections,
+// leaving out entries it already lists; other releases are inserted in
+// version order below Unreleased. It returns the number of entries added.
+func (f *File) Merge(opts Options, update *File) int {
+	added := 0
+	for _, u := range update.Releases {
+		items := parseItems(u.Body)
+		if r := f.Release(u.Version()); r != nil {
+			added += r.merge(opts, items)
+			continue
+		}
+		f.insert(&Release{Heading: u.Heading, Body: u.Body})
+		for _, s := range items {
+			added += len(s.items)
+		}
+	}
+	return added
+}
+
+// insert places a new release in order: Unreleased first, then versions
+// newest first
+func (f *File) insert(r *Release) {
+	i := 0
+	for ; i < len(f.Releases); i++ {
+		existing := f.Releases[i].Version()
+		if strings.EqualFold(r.Version(), Unreleased) ||
+			(!strings.EqualFold(existing, Unreleased) && compareVersions(r.Version(), existing) > 0) {
+			break
+		}
+	}
+
+	// Keep a blank line between the new release and the text before it
+	previous, text := &f.Preamble, f.Preamble
+	if i > 0 {
+		previous = &f.Releases[i-1].Body
+		text = f.Releases[i-1].String()
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		*previous += "\n"
+	}
+	if text != "" && !strings.HasSuffix(text, "\n\n") {
+		*previous += "\n"
+	}
+	if i == len(f.Releases) && f.Links == "" {
+		// Nothing follows, so the release ends the file without a blank line
+		r.Body = strings.TrimRight(r.Body, "\n") + "\n"
+	} else if !strings.HasSuffix(r.Body, "\n\n") {
+		r.Body = strings.TrimRight(r.Body, "\n") + "\n\n"
+	}
+
+	f.Releases = append(f.Releases, nil)
+	copy(f.Releases[i+1:], f.Releases[i:])
+	f.Releases[i] = r
+}
+
+// sectionItems are the list items under a "### " heading
+type sectionItems struct {
+	name  string
+	items []string
+}
+
+// parseItems returns the list items of a release body by section. An item
+// is its first line and the indented lines that continue it.
+func parseItems(body string) []sectionItems {
+	var sections []sectionItems
+	for _, line := range strings.SplitAfter(body, "\n") {
+		switch {
+		case strings.HasPrefix(line, "### "):
+			sections = append(sections, sectionItems{name: strings.TrimSpace(line[4:])})
+		case len(sections) == 0 || strings.TrimSpace(line) == "":
+		case itemPattern.MatchString(line):
+			s := &sections[len(sections)-1]
+			s.items = append(s.items, strings.TrimRight(line, "\r\n")+"\n")
+		case line[0] == ' ' || line[0] == '\t':
+			if s := &sections[len(sections)-1]; len(s.items) > 0 {
+				s.items[len(s.items)-1] += strings.TrimRight(line, "\r\n") + "\n"
+			}
+		}
+	}
+	return sections
+}
+
+// itemKey returns the text an item is compared by: without its marker, in
+// lower case and with spaces collapsed
+func itemKey(item string) string {
+	return strings.ToLower(strings.Join(strings.Fields(itemPattern.ReplaceAllString(item, "")), " "))
+}
+
+// merge adds the items the release does not list yet, each to the end of
+// its section, and returns how many it added. A missing section is created
+// before the first section that comes after it in order.
+func (r *Release) merge(opts Options, update []sectionItems) int {
+	lines := strings.SplitAfter(r.Body, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	seen := map[string]bool{}
+	for _, s := range parseItems(r.Body) {
+		for _, item := range s.items {
+			seen[itemKey(item)] = true
+		}
+	}
+
+	added := 0
+	for _, u := range update {
+		var items []string
+		for _, item := range u.items {
+			if key := itemKey(item); !seen[key] {
+				seen[key] = true
+				items = append(items, item)
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+		added += len(items)
+		lines = insertItems(opts, lines, u.name, items)
+	}
+	r.Body = strings.Join(lines, "")
+	return added
+}
+
+// insertItems adds items after the last item of the named section in
+// lines, creating the section when there is none
+func insertItems(opts Options, lines []string, name string, items []string) []string {
+	var headings []int
+	for i, line := range lines {
+		if strings.HasPrefix(line, "### ") {
+			headings = append(headings, i)
+		}
+	}
+	for n, h := range headings {
+		if Key(strings.TrimSpace(lines[h][4:])) == Key(name) {
+			end := len(lines)
+			if n+1 < len(headings) {
+				end = headings[n+1]
+			}
+			return splice(lines, lastItem(lines, h, end), items)
+		}
+	}
+
+	block := append([]string{"### " + name + "\n"}, items...)
+	order := sectionOrder(opts, name)
+	for _, h := range headings {
+		if sectionOrder(opts, strings.TrimSpace(lines[h][4:])) > order {
+			return splice(lines, h, append(block, "\n"))
+		}
+	}
+	at := lastText(lines, 0, len(lines))
+	if len(headings) > 0 {
+		// After the last section's items, before any text that follows them
+		at = lastItem(lines, headings[len(headings)-1], len(lines))
+	}
+	return splice(lines, at, append([]string{"\n"}, block...))
+}
+
+// lastItem returns the index after the last list item of the section whose
+// heading is at lines[heading], ending before lines[end], or after the
+// heading when the section has no items
+func lastItem(lines []string, heading, end int) int {
+	last, inItem := heading+1, false
+	for i := heading + 1; i < end; i++ {
+		line := lines[i]
+		switch {
+		case itemPattern.MatchString(line):
+			last, inItem = i+1, true
+		case strings.TrimSpace(line) == "":
+		case inItem && (line[0] == ' ' || line[0] == '\t'):
+			last = i + 1
+		default:
+			inItem = false
+		}
+	}
+	return last
+}
+
+// sectionOrder returns the position of a section in the configured order;
+// unknown sections come last
+func sectionOrder(opts Options, name string) int {
+	all := opts.All()
+	for i, s := range all {
+		if Key(s.Name) == Key(name) {
+			return i
+		}
+	}
+	return len(all)
+}
+
+// lastText returns the index after the last line in lines[from:to] that is
+// not blank, or from when they all are
+func lastText(lines []string, from, to int) int {
+	for i := to; i > from; i-- {
+		if strings.TrimSpace(lines[i-1]) != "" {
+			return i
+		}
+	}
+	return from
+}
+
+// splice inserts add into lines at i
+func splice(lines []string, i int, add []string) []string {
+	out := make([]string, 0, len(lines)+len(add))
+	out = append(out, lines[:i]...)
+	out = append(out, add...)
+	return append(out, lines[i:]...)
+}
+
+// compareVersions compares the numbers in two versions, e.g. 1.10.0 is
+// after 1.9.2, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	as, bs := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionNumbers returns the numbers in a version before any pre-release
+// or build suffix
+func versionNumbers(version string) []int {
+	version = strings.TrimPrefix(strings.TrimPrefix(version, "v"), "V")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	var numbers []int
+	for _, part := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(part)
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
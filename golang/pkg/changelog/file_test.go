@@ -0,0 +1,172 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+)
+
+const testFile = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+### Added
+- Retries for failed uploads
+  with a backoff
+
+### Fixed
+- Crash on empty input
+
+## [1.1.0] - 2024-05-01
+
+### Added
+- Export to CSV
+
+` + "```markdown\n## Not a release\n```\n" + `
+## 1.0.0
+
+- First release
+
+[Unreleased]: https://github.com/o/r/compare/v1.1.0...HEAD
+[1.1.0]: https://github.com/o/r/compare/v1.0.0...v1.1.0
+`
+
+func TestParseFile(t *testing.T) {
+	f := ParseFile(testFile)
+	if got := f.String(); got != testFile {
+		t.Errorf("Expected the file back unchanged, got:\n%s", got)
+	}
+
+	var versions []string
+	for _, r := range f.Releases {
+		versions = append(versions, r.Version())
+	}
+	if len(versions) != 3 || versions[0] != "Unreleased" || versions[1] != "1.1.0" || versions[2] != "1.0.0" {
+		t.Errorf("Unexpected releases %q", versions)
+	}
+	if f.Preamble != "# Changelog\n\nAll notable changes to this project will be documented in this file.\n\n" {
+		t.Errorf("Unexpected preamble %q", f.Preamble)
+	}
+	if f.Links != "[Unreleased]: https://github.com/o/r/compare/v1.1.0...HEAD\n[1.1.0]: https://github.com/o/r/compare/v1.0.0...v1.1.0\n" {
+		t.Errorf("Unexpected links %q", f.Links)
+	}
+
+	// Text that is not Keep a Changelog is kept as it is
+	for _, text := range []string{"", "Notes\r\n\r\n## [Unreleased]\r\n- a", "## 2.0.0\n\n---\n\n---\n\n## [Unreleased]\n"} {
+		if got := ParseFile(text).String(); got != text {
+			t.Errorf("Expected %q back, got %q", text, got)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		update   string
+		added    int
+		expected string
+	}{
+		{
+			name:     "Existing sections",
+			existing: testFile,
+			update:   "## [Unreleased]\n\n### Added\n- Dark mode\n- retries for failed  uploads with a backoff\n\n### Deprecated\n- The v1 API\n\n### Security\n- Escape HTML in titles\n\n",
+			added:    3,
+			expected: `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+### Added
+- Retries for failed uploads
+  with a backoff
+- Dark mode
+
+### Deprecated
+- The v1 API
+
+### Fixed
+- Crash on empty input
+
+### Security
+- Escape HTML in titles
+
+` + testFile[strings.Index(testFile, "## [1.1.0]"):],
+		},
+		{
+			name:     "Nothing new",
+			existing: testFile,
+			update:   "## [Unreleased]\n\n### Fixed\n- Crash on empty input\n\n",
+			expected: testFile,
+		},
+		{
+			name:     "No Unreleased section",
+			existing: "# Changelog\n\nIntro.\n\n## [1.0.0] - 2024-01-01\n\n### Added\n- First\n\n[1.0.0]: https://example.com\n",
+			update:   "## [Unreleased]\n\n### Fixed\n- Second\n\n",
+			added:    1,
+			expected: "# Changelog\n\nIntro.\n\n## [Unreleased]\n\n### Fixed\n- Second\n\n## [1.0.0] - 2024-01-01\n\n### Added\n- First\n\n[1.0.0]: https://example.com\n",
+		},
+		{
+			name:     "Empty file",
+			update:   "## [Unreleased]\n\n### Added\n- First\n\n",
+			added:    1,
+			expected: "## [Unreleased]\n\n### Added\n- First\n",
+		},
+		{
+			name:     "Releases in version order",
+			existing: "# Changelog\n\n## [Unreleased]\n\n## [1.0.0]\n\n### Added\n- First\n",
+			update:   "## [1.10.0] - 2024-03-01\n\n### Added\n- Third\n\n## [1.9.0] - 2024-02-01\n\n### Added\n- Second\n\n## [1.0.0]\n\n### Added\n- First\n\n",
+			added:    2,
+			expected: "# Changelog\n\n## [Unreleased]\n\n## [1.10.0] - 2024-03-01\n\n### Added\n- Third\n\n## [1.9.0] - 2024-02-01\n\n### Added\n- Second\n\n## [1.0.0]\n\n### Added\n- First\n",
+		},
+		{
+			name:     "Text after the last section",
+			existing: "## [Unreleased]\n\n### Added\n- First\n\n---\n\n## 1.0.0\n",
+			update:   "## [Unreleased]\n\n### Fixed\n- Second\n\n",
+			added:    1,
+			expected: "## [Unreleased]\n\n### Added\n- First\n\n### Fixed\n- Second\n\n---\n\n## 1.0.0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := ParseFile(tt.existing)
+			added := f.Merge(Options{}, ParseFile(tt.update))
+			if got := f.String(); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+			if added != tt.added {
+				t.Errorf("Expected %d entries added, got %d", tt.added, added)
+			}
+		})
+	}
+}
+
+func TestMergeCustomSection(t *testing.T) {
+	opts := Options{Sections: []Section{{Name: "Performance"}}}
+	f := ParseFile("## [Unreleased]\n\n### Fixed\n- A\n\n### Other\n- B\n")
+	f.Merge(opts, ParseFile("## [Unreleased]\n\n### Performance\n- C\n\n### Added\n- D\n"))
+	expected := "## [Unreleased]\n\n### Added\n- D\n\n### Fixed\n- A\n\n### Performance\n- C\n\n### Other\n- B\n"
+	if got := f.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.10.0", "1.9.2", 1},
+		{"1.0", "1.0.0", 0},
+		{"v2.0.0", "1.9.9", 1},
+		{"1.0.0-rc.1", "1.0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.expected {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
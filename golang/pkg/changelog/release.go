@@ -0,0 +1,70 @@
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// comparePattern matches a compare link such as
+// "https://github.com/o/r/compare/v1.1.0...HEAD"
+var comparePattern = regexp.MustCompile(`^(.*/compare/)([^/]+?)\.\.\.(\S+)$`)
+
+// Cut releases the Unreleased section as version, dated date: its heading
+// becomes "[version] - date" and an empty Unreleased section is added above
+// it. When the Unreleased link reference is a compare link, it is moved on
+// to tag and a compare link from the previous tag to tag is added for the
+// release. It returns the released section.
+func (f *File) Cut(version, tag, date string) (*Release, error) {
+	r := f.Release(Unreleased)
+	if r == nil {
+		return nil, fmt.Errorf("the changelog has no Unreleased section")
+	}
+	if strings.TrimSpace(r.Body) == "" {
+		return nil, fmt.Errorf("the Unreleased section is empty")
+	}
+	if f.Release(version) != nil {
+		return nil, fmt.Errorf("the changelog already has a section for %s", version)
+	}
+
+	r.Heading = fmt.Sprintf("[%s] - %s", version, date)
+	i := 0
+	for f.Releases[i] != r {
+		i++
+	}
+	f.Releases = append(f.Releases, nil)
+	copy(f.Releases[i+1:], f.Releases[i:])
+	f.Releases[i] = &Release{Heading: "[" + Unreleased + "]", Body: "\n"}
+	f.Links = releaseLinks(f.Links, version, tag)
+	return r, nil
+}
+
+// releaseLinks updates the link references for the release of version as
+// tag. The Unreleased compare link gives the repository and the previous
+// tag; without one the links are returned unchanged.
+func releaseLinks(links, version, tag string) string {
+	lines := strings.SplitAfter(links, "\n")
+	for i, line := range lines {
+		m := linkPattern.FindStringSubmatch(line)
+		if m == nil || !strings.EqualFold(m[1], Unreleased) {
+			continue
+		}
+		c := comparePattern.FindStringSubmatch(m[2])
+		if c == nil {
+			return links
+		}
+		base, previous, head := c[1], c[2], c[3]
+		newline := line[len(strings.TrimRight(line, "\r\n")):]
+		if newline == "" {
+			newline = "\n"
+		}
+		unreleased := strings.Replace(strings.TrimRight(line, "\r\n"), m[2], base+tag+"..."+head, 1) + newline
+		release := fmt.Sprintf("[%s]: %s%s...%s", version, base, previous, tag) + newline
+		if i == len(lines)-1 && !strings.HasSuffix(line, "\n") {
+			// The last line had no newline; keep it that way
+			release = strings.TrimRight(release, "\r\n")
+		}
+		return strings.Join(lines[:i], "") + unreleased + release + strings.Join(lines[i+1:], "")
+	}
+	return links
+}
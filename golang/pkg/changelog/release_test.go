@@ -0,0 +1,67 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCut(t *testing.T) {
+	f := ParseFile(testFile)
+	r, err := f.Cut("1.2.0", "v1.2.0", "2024-06-01")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r.Heading != "[1.2.0] - 2024-06-01" || !strings.HasPrefix(r.Body, "\n### Added\n- Retries") {
+		t.Errorf("Unexpected release %q", r.String())
+	}
+
+	expected := strings.Replace(testFile, "## [Unreleased]\n", "## [Unreleased]\n\n## [1.2.0] - 2024-06-01\n", 1)
+	expected = strings.Replace(expected, "[Unreleased]: https://github.com/o/r/compare/v1.1.0...HEAD\n",
+		"[Unreleased]: https://github.com/o/r/compare/v1.2.0...HEAD\n[1.2.0]: https://github.com/o/r/compare/v1.1.0...v1.2.0\n", 1)
+	if got := f.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// The new Unreleased section is empty
+	if _, err := f.Cut("1.3.0", "v1.3.0", "2024-07-01"); err == nil {
+		t.Error("Expected an error for an empty Unreleased section")
+	}
+	if _, err := ParseFile("## [1.0.0]\n\n- First\n").Cut("1.1.0", "v1.1.0", "2024-07-01"); err == nil {
+		t.Error("Expected an error without an Unreleased section")
+	}
+	if _, err := ParseFile("## [Unreleased]\n\n- Second\n\n## [1.0.0]\n\n- First\n").Cut("1.0.0", "v1.0.0", "2024-07-01"); err == nil {
+		t.Error("Expected an error for a version that is already released")
+	}
+}
+
+func TestReleaseLinks(t *testing.T) {
+	tests := []struct {
+		name, links, expected string
+	}{
+		{
+			name:     "Compare link",
+			links:    "[Unreleased]: https://gitlab.com/o/r/-/compare/1.0...main\n[1.0]: https://gitlab.com/o/r/-/tags/1.0\n",
+			expected: "[Unreleased]: https://gitlab.com/o/r/-/compare/v2.0.0...main\n[2.0.0]: https://gitlab.com/o/r/-/compare/1.0...v2.0.0\n[1.0]: https://gitlab.com/o/r/-/tags/1.0\n",
+		},
+		{
+			name:     "No final newline",
+			links:    "[unreleased]: https://github.com/o/r/compare/v1.0.0...HEAD",
+			expected: "[unreleased]: https://github.com/o/r/compare/v2.0.0...HEAD\n[2.0.0]: https://github.com/o/r/compare/v1.0.0...v2.0.0",
+		},
+		{
+			name:     "Not a compare link",
+			links:    "[Unreleased]: https://github.com/o/r/commits/HEAD\n",
+			expected: "[Unreleased]: https://github.com/o/r/commits/HEAD\n",
+		},
+		{
+			name: "No links",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := releaseLinks(tt.links, "2.0.0", "v2.0.0"); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
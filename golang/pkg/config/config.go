@@ -0,0 +1,383 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/bedrock"
+	"github.com/gudlyf/GudCommit/golang/pkg/changelog"
+	"github.com/gudlyf/GudCommit/golang/pkg/convention"
+	"github.com/gudlyf/GudCommit/golang/pkg/diff"
+	"github.com/gudlyf/GudCommit/golang/pkg/redact"
+)
+
+// SourceDefault marks values that come from the built-in defaults
+const SourceDefault = "default"
+
+// userFiles are checked in order in the home directory; the first found wins
+var userFiles = []string{".gudcommit.json", ".gudchangelog.json"}
+
+// repoFiles are checked in order at the repository top level
+var repoFiles = []string{".gudcommit.json", ".gudcommit.yaml", ".gudcommit.yml"}
+
+// repoKeys are the settings a repository file may change. A cloned
+// repository is not trusted with where the diff is sent or with credentials,
+// so its other settings are ignored, and its redact settings may only make
+// redaction stricter.
+var repoKeys = map[string]bool{"commit": true, "diff": true, "changelog": true, "model_id": true, "redact": true}
+
+// warnings receives notes about settings that are ignored
+var warnings io.Writer = os.Stderr
+
+// envVars maps environment variables onto config keys
+var envVars = []struct {
+	name string
+	key  string
+	kind string
+}{
+	{name: "GUD_PROVIDER", key: "provider", kind: "string"},
+	{name: "GUD_BEDROCK_MODEL_ID", key: "model_id", kind: "string"},
+	{name: "GUD_HTTP_TIMEOUT_SECONDS", key: "timeout_seconds", kind: "int"},
+	{name: "AWS_REGION", key: "region", kind: "string"},
+	{name: "GUD_BEDROCK_AUTH", key: "auth", kind: "string"},
+	{name: "GUD_STREAM", key: "stream", kind: "bool"},
+	{name: "GUD_PROGRESS", key: "progress", kind: "string"},
+	{name: "GUD_MAX_ATTEMPTS", key: "retry.max_attempts", kind: "int"},
+	{name: "GUD_REVIEW", key: "review", kind: "string"},
+}
+
+// Values for the "review" key
+const (
+	// ReviewTUI shows the full-screen review when stdin and stdout are terminals
+	ReviewTUI = "tui"
+	// ReviewPrompt always asks with a one-line prompt
+	ReviewPrompt = "prompt"
+)
+
+// Config is the effective configuration shared by gudcommit and gudchangelog.
+// It is built from layers with precedence flags > env > repo file > user
+// file > defaults, and remembers which layer set each value.
+type Config struct {
+	bedrock.Config
+	// Commit holds the commit type and scope rules
+	Commit convention.Rules `json:"commit"`
+	// Diff controls how large diffs are budgeted before prompting
+	Diff diff.Options `json:"diff"`
+	// Redact controls secret redaction before prompting
+	Redact redact.Options `json:"redact"`
+	// Review is how gudcommit asks for confirmation: "tui" or "prompt"
+	Review string `json:"review"`
+	// Changelog configures the sections gudchangelog writes
+	Changelog changelog.Options `json:"changelog"`
+	sources   map[string]string
+}
+
+// Load builds the configuration for the repository at repoRoot. An empty
+// repoRoot skips the repository file.
+func Load(repoRoot string) (*Config, error) {
+	cfg := Defaults()
+
+	if home, err := os.UserHomeDir(); err == nil {
+		path, values, err := readFirstFile(home, userFiles)
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			if err := cfg.Apply("user file "+path, values); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if repoRoot != "" {
+		path, values, err := readFirstFile(repoRoot, repoFiles)
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			if err := cfg.applyRepo("repo file "+path, values); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, env := range envVars {
+		v := os.Getenv(env.name)
+		if v == "" {
+			continue
+		}
+		value, err := convertEnv(v, env.kind)
+		if err != nil {
+			// Ignore malformed numeric overrides as the original loader did
+			continue
+		}
+		if err := cfg.Apply("env "+env.name, nested(env.key, value)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Commit.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Changelog.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Review != ReviewTUI && cfg.Review != ReviewPrompt {
+		return nil, fmt.Errorf("unknown review %q (expected %s or %s)", cfg.Review, ReviewTUI, ReviewPrompt)
+	}
+
+	return cfg, nil
+}
+
+// Defaults returns a configuration holding only the built-in defaults
+func Defaults() *Config {
+	cfg := &Config{
+		Config:    bedrock.DefaultConfig(),
+		Commit:    convention.DefaultRules(),
+		Diff:      diff.DefaultOptions(),
+		Redact:    redact.DefaultOptions(),
+		Review:    ReviewTUI,
+		Changelog: changelog.DefaultOptions(),
+		sources:   map[string]string{},
+	}
+	values, _ := toMap(cfg)
+	for key := range flatten("", values) {
+		cfg.sources[key] = SourceDefault
+	}
+	cfg.resolveModel()
+	return cfg
+}
+
+// Apply merges values (keyed like the JSON config file) over the current
+// configuration and records source as their origin.
+func (c *Config) Apply(source string, values map[string]interface{}) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", source, err)
+	}
+	// Unmarshalling into the existing struct only replaces the keys present,
+	// so nested objects such as "retry" merge field by field.
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("invalid configuration in %s: %w", source, err)
+	}
+	for key := range flatten("", values) {
+		c.sources[key] = source
+	}
+	c.resolveModel()
+	return nil
+}
+
+// Source returns where the value for key (e.g. "model_id" or
+// "retry.max_attempts") came from.
+func (c *Config) Source(key string) string {
+	return c.sources[key]
+}
+
+// Print writes every effective setting with its source
+func (c *Config) Print(w io.Writer) error {
+	values, err := toMap(c)
+	if err != nil {
+		return err
+	}
+	flat := flatten("", values)
+
+	keys := make([]string, 0, len(flat))
+	width := 0
+	for key := range flat {
+		keys = append(keys, key)
+		if len(key) > width {
+			width = len(key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, _ := json.Marshal(flat[key])
+		source := c.sources[key]
+		if source == "" {
+			source = SourceDefault
+		}
+		if _, err := fmt.Fprintf(w, "%-*s = %s  (%s)\n", width, key, value, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveModel picks the provider's default model unless one was configured
+func (c *Config) resolveModel() {
+	if c.ModelID == "" || c.sources["model_id"] == SourceDefault {
+		c.ModelID = bedrock.DefaultModelID(c.Provider)
+		c.sources["model_id"] = SourceDefault
+	}
+}
+
+// readFirstFile reads the first of names that exists in dir. It returns an
+// empty path when there is none.
+func readFirstFile(dir string, names []string) (string, map[string]interface{}, error) {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open config file %s: %w", path, err)
+		}
+
+		values, err := decodeFile(path, data)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decode config file %s: %w", path, err)
+		}
+		return path, values, nil
+	}
+	return "", nil, nil
+}
+
+// applyRepo applies the settings of a repository file that repoKeys allow,
+// and warns about the rest
+func (c *Config) applyRepo(source string, values map[string]interface{}) error {
+	allowed := map[string]interface{}{}
+	var ignored []string
+	for key, value := range values {
+		if !repoKeys[key] {
+			ignored = append(ignored, key)
+			continue
+		}
+		if redact, ok := value.(map[string]interface{}); ok && key == "redact" {
+			var dropped []string
+			value, dropped = c.stricterRedact(redact)
+			ignored = append(ignored, dropped...)
+		}
+		allowed[key] = value
+	}
+
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		fmt.Fprintf(warnings, "⚠ :: Ignoring %s in %s: a repository file may only set commit, diff, changelog, model_id and stricter redact settings\n",
+			strings.Join(ignored, ", "), source)
+	}
+	return c.Apply(source, allowed)
+}
+
+// stricterRedact returns the redact settings that make redaction stricter
+// than it is, and the names of the others. Patterns are added to the ones
+// already configured.
+func (c *Config) stricterRedact(values map[string]interface{}) (map[string]interface{}, []string) {
+	stricter := map[string]interface{}{}
+	var ignored []string
+	for key, value := range values {
+		// Values of the wrong type are kept for Apply to report
+		switch key {
+		case "enabled", "abort":
+			if on, ok := value.(bool); ok && !on {
+				ignored = append(ignored, "redact."+key)
+				continue
+			}
+		case "patterns":
+			if patterns, ok := value.([]interface{}); ok {
+				combined := make([]interface{}, 0, len(c.Redact.Patterns)+len(patterns))
+				for _, p := range c.Redact.Patterns {
+					combined = append(combined, p)
+				}
+				value = append(combined, patterns...)
+			}
+		case "entropy_threshold":
+			if threshold, ok := toFloat(value); ok && (threshold <= 0 || (c.Redact.EntropyThreshold > 0 && threshold > c.Redact.EntropyThreshold)) {
+				ignored = append(ignored, "redact."+key)
+				continue
+			}
+		default:
+			ignored = append(ignored, "redact."+key)
+			continue
+		}
+		stricter[key] = value
+	}
+	return stricter, ignored
+}
+
+// toFloat returns a decoded JSON or YAML number as a float64
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// decodeFile parses a JSON or YAML config file into a generic map
+func decodeFile(path string, data []byte) (map[string]interface{}, error) {
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		return parseYAML(string(data))
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// convertEnv converts an environment variable to the type of its key
+func convertEnv(v, kind string) (interface{}, error) {
+	switch kind {
+	case "int":
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid number %q", v)
+		}
+		return n, nil
+	case "bool":
+		return v == "1" || strings.EqualFold(v, "true"), nil
+	}
+	return v, nil
+}
+
+// nested turns a dotted key such as "retry.max_attempts" into nested maps
+func nested(key string, value interface{}) map[string]interface{} {
+	parts := strings.Split(key, ".")
+	m := map[string]interface{}{parts[len(parts)-1]: value}
+	for i := len(parts) - 2; i >= 0; i-- {
+		m = map[string]interface{}{parts[i]: m}
+	}
+	return m
+}
+
+// flatten returns the leaf values of a nested map keyed by dotted path
+func flatten(prefix string, values map[string]interface{}) map[string]interface{} {
+	flat := map[string]interface{}{}
+	for key, value := range values {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			for k, v := range flatten(path, child) {
+				flat[k] = v
+			}
+			continue
+		}
+		flat[path] = value
+	}
+	return flat
+}
+
+// toMap converts the configuration to a generic map via its JSON form
+func toMap(c *Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
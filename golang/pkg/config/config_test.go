@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolate points HOME at an empty directory and clears config env vars
+func isolate(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range envVars {
+		t.Setenv(env.name, "")
+	}
+	old := warnings
+	warnings = io.Discard
+	t.Cleanup(func() { warnings = old })
+	return home
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	isolate(t)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Provider != "bedrock" || cfg.TimeoutSeconds != 60 || cfg.Retry.MaxAttempts != 4 {
+		t.Errorf("Unexpected defaults: %+v", cfg.Config)
+	}
+	if cfg.ModelID == "" {
+		t.Error("Expected a default model ID")
+	}
+	if src := cfg.Source("provider"); src != SourceDefault {
+		t.Errorf("Expected default source, got %q", src)
+	}
+	if cfg.Review != ReviewTUI {
+		t.Errorf("Expected the review screen by default, got %q", cfg.Review)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	home := isolate(t)
+	repo := t.TempDir()
+
+	writeFile(t, filepath.Join(home, ".gudcommit.json"), `{
+  "provider": "openai",
+  "region": "eu-west-1",
+  "timeout_seconds": 30,
+  "retry": {"max_attempts": 6, "base_delay_ms": 500}
+}`)
+	writeFile(t, filepath.Join(repo, ".gudcommit.yaml"), `
+# repository settings
+model_id: gpt-4o
+diff:
+  max_tokens: 9000
+`)
+	t.Setenv("GUD_HTTP_TIMEOUT_SECONDS", "120")
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	checks := []struct {
+		key    string
+		got    interface{}
+		want   interface{}
+		source string
+	}{
+		{"provider", cfg.Provider, "openai", "user file " + filepath.Join(home, ".gudcommit.json")},
+		{"region", cfg.Region, "eu-west-1", "user file " + filepath.Join(home, ".gudcommit.json")},
+		{"timeout_seconds", cfg.TimeoutSeconds, 120, "env GUD_HTTP_TIMEOUT_SECONDS"},
+		{"retry.max_attempts", cfg.Retry.MaxAttempts, 6, "user file " + filepath.Join(home, ".gudcommit.json")},
+		{"retry.base_delay_ms", cfg.Retry.BaseDelayMs, 500, "user file " + filepath.Join(home, ".gudcommit.json")},
+		{"retry.max_delay_ms", cfg.Retry.MaxDelayMs, 20000, SourceDefault},
+		{"model_id", cfg.ModelID, "gpt-4o", "repo file " + filepath.Join(repo, ".gudcommit.yaml")},
+		{"diff.max_tokens", cfg.Diff.MaxTokens, 9000, "repo file " + filepath.Join(repo, ".gudcommit.yaml")},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.key, c.want, c.got)
+		}
+		if src := cfg.Source(c.key); src != c.source {
+			t.Errorf("%s: expected source %q, got %q", c.key, c.source, src)
+		}
+	}
+}
+
+func TestLoadRepoJSONPreferredOverYAML(t *testing.T) {
+	isolate(t)
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ".gudcommit.json"), `{"model_id": "model-a"}`)
+	writeFile(t, filepath.Join(repo, ".gudcommit.yaml"), "model_id: model-b\n")
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.ModelID != "model-a" {
+		t.Errorf("Expected model from .gudcommit.json, got %s", cfg.ModelID)
+	}
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	home := isolate(t)
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ".gudcommit.json"), `{"diff": {"max_tokens": "soon"}}`)
+
+	if _, err := Load(repo); err == nil {
+		t.Error("Expected error for invalid config value")
+	}
+
+	writeFile(t, filepath.Join(home, ".gudcommit.json"), `{"review": "fancy"}`)
+	if _, err := Load(""); err == nil {
+		t.Error("Expected error for unknown review")
+	}
+}
+
+func TestLoadRepoFileRestricted(t *testing.T) {
+	home := isolate(t)
+	repo := t.TempDir()
+	var warned bytes.Buffer
+	warnings = &warned
+
+	writeFile(t, filepath.Join(home, ".gudcommit.json"), `{"redact": {"patterns": ["internal-[0-9]+"], "entropy_threshold": 4.0}}`)
+	writeFile(t, filepath.Join(repo, ".gudcommit.json"), `{
+  "endpoint": "https://evil.example",
+  "api_key_env": "AWS_SECRET_ACCESS_KEY",
+  "provider": "anthropic",
+  "auth": "bearer",
+  "model_id": "team-model",
+  "redact": {"enabled": false, "abort": true, "patterns": ["ticket-[0-9]+"], "entropy_threshold": 9}
+}`)
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defaults := Defaults()
+	if cfg.Endpoint != defaults.Endpoint || cfg.APIKeyEnv != defaults.APIKeyEnv || cfg.Provider != defaults.Provider || cfg.Auth != defaults.Auth {
+		t.Errorf("Expected the connection settings to be ignored, got %+v", cfg.Config)
+	}
+	if cfg.ModelID != "team-model" {
+		t.Errorf("Expected the repository's model, got %q", cfg.ModelID)
+	}
+	if !cfg.Redact.Enabled || !cfg.Redact.Abort || cfg.Redact.EntropyThreshold != 4.0 {
+		t.Errorf("Expected redaction to only get stricter, got %+v", cfg.Redact)
+	}
+	if got := strings.Join(cfg.Redact.Patterns, ","); got != "internal-[0-9]+,ticket-[0-9]+" {
+		t.Errorf("Expected the repository's patterns added to the user's, got %s", got)
+	}
+	for _, key := range []string{"api_key_env", "auth", "endpoint", "provider", "redact.enabled", "redact.entropy_threshold"} {
+		if !strings.Contains(warned.String(), key) {
+			t.Errorf("Expected a warning about %s, got %q", key, warned.String())
+		}
+	}
+}
+
+func TestLoadCommitRules(t *testing.T) {
+	home := isolate(t)
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(home, ".gudcommit.json"), `{"commit": {"scope_strategy": "directory"}}`)
+	writeFile(t, filepath.Join(repo, ".gudcommit.yaml"), `
+commit:
+  types: [feat, fix, revert, security, deps]
+  scopes:
+    - api
+    - cli
+`)
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.Join(cfg.Commit.Types, ","); got != "feat,fix,revert,security,deps" {
+		t.Errorf("Expected repo types, got %s", got)
+	}
+	if cfg.Commit.ScopeStrategy != "directory" || len(cfg.Commit.Scopes) != 2 {
+		t.Errorf("Expected merged commit rules, got %+v", cfg.Commit)
+	}
+
+	writeFile(t, filepath.Join(repo, ".gudcommit.yaml"), "commit:\n  scope_strategy: module\n")
+	if _, err := Load(repo); err == nil {
+		t.Error("Expected error for unknown scope strategy")
+	}
+}
+
+func TestLoadChangelogSections(t *testing.T) {
+	isolate(t)
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ".gudcommit.yaml"), `
+changelog:
+  sections:
+    - name: Performance
+      description: Speed and memory improvements
+      types: [perf]
+`)
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(cfg.Changelog.Sections) != 1 || cfg.Changelog.Sections[0].Name != "Performance" || cfg.Changelog.Sections[0].Types[0] != "perf" {
+		t.Errorf("Expected the custom section, got %+v", cfg.Changelog)
+	}
+
+	writeFile(t, filepath.Join(repo, ".gudcommit.yaml"), "changelog:\n  sections:\n    - name: fixed\n")
+	if _, err := Load(repo); err == nil {
+		t.Error("Expected error for a section that clashes with a standard one")
+	}
+}
+
+func TestApplyFlags(t *testing.T) {
+	isolate(t)
+	t.Setenv("GUD_BEDROCK_MODEL_ID", "env-model")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cfg.Apply("flag --model", map[string]interface{}{"model_id": "flag-model"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.ModelID != "flag-model" || cfg.Source("model_id") != "flag --model" {
+		t.Errorf("Expected flag to win, got %s from %s", cfg.ModelID, cfg.Source("model_id"))
+	}
+
+	// A configured model is kept when the provider changes afterwards
+	if err := cfg.Apply("flag --provider", map[string]interface{}{"provider": "openai"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.ModelID != "flag-model" {
+		t.Errorf("Expected configured model to be kept, got %s", cfg.ModelID)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	isolate(t)
+	t.Setenv("GUD_STREAM", "true")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Compare with the column padding collapsed
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	out := strings.Join(lines, "\n")
+	for _, want := range []string{
+		`stream = true (env GUD_STREAM)`,
+		`retry.max_attempts = 4 (default)`,
+		`provider = "bedrock" (default)`,
+		`commit.types = ["feat","fix","build","chore","ci","docs","style","refactor","perf","test"] (default)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a non-blank, non-comment line with its indentation
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// parseYAML decodes the subset of YAML used by config files: nested block
+// mappings, block sequences (of scalars or mappings), flow sequences such as
+// [a, b] and plain, single- or double-quoted scalars. Anchors, multi-line
+// strings and multiple documents are not supported.
+func parseYAML(data string) (map[string]interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		text := stripComment(raw)
+		if strings.TrimSpace(text) == "" || strings.TrimSpace(text) == "---" {
+			continue
+		}
+		if lead := text[:len(text)-len(strings.TrimLeft(text, " \t"))]; strings.Contains(lead, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		trimmed := strings.TrimLeft(text, " ")
+		lines = append(lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: strings.TrimRight(trimmed, " ")})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	value, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("top level must be a mapping")
+	}
+	return m, nil
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseBlock parses a mapping or sequence whose entries start at indent
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if strings.HasPrefix(p.lines[p.pos].text, "- ") || p.lines[p.pos].text == "-" {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+		if strings.HasPrefix(line.text, "- ") {
+			return nil, fmt.Errorf("line %d: sequence item where a key was expected", line.num)
+		}
+
+		key, rest, err := splitKey(line)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+
+		if rest != "" {
+			value, err := parseFlow(rest, line.num)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+			continue
+		}
+
+		// Nested block, or null when nothing is indented beneath the key.
+		// Sequences may sit at the same indentation as their key.
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && strings.HasPrefix(next.text, "- ")) {
+				value, err := p.parseBlock(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				m[key] = value
+				continue
+			}
+		}
+		m[key] = nil
+	}
+	return m, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	var items []interface{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || !(strings.HasPrefix(line.text, "- ") || line.text == "-") {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+
+		item := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if item == "" {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				value, err := p.parseBlock(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, value)
+			} else {
+				items = append(items, nil)
+			}
+			continue
+		}
+
+		// "- key: value" starts a mapping whose keys align with "key"
+		if _, _, err := splitKey(yamlLine{num: line.num, text: item}); err == nil && !isQuoted(item) && !strings.HasPrefix(item, "[") && !strings.HasPrefix(item, "{") {
+			childIndent := line.indent + len(line.text) - len(item)
+			p.lines[p.pos] = yamlLine{num: line.num, indent: childIndent, text: item}
+			value, err := p.parseMapping(childIndent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+			continue
+		}
+
+		value, err := parseFlow(item, line.num)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+		p.pos++
+	}
+	return items, nil
+}
+
+// splitKey splits "key: rest" and unquotes the key
+func splitKey(line yamlLine) (string, string, error) {
+	text := line.text
+	var key string
+	if isQuoted(text) {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", fmt.Errorf("line %d: unterminated quoted key", line.num)
+		}
+		key = text[1 : end+1]
+		text = text[end+2:]
+		if !strings.HasPrefix(text, ":") {
+			return "", "", fmt.Errorf("line %d: expected ':' after key", line.num)
+		}
+		return key, strings.TrimSpace(text[1:]), nil
+	}
+
+	idx := strings.Index(text, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", fmt.Errorf("line %d: expected 'key: value'", line.num)
+		}
+		idx = len(text) - 1
+	}
+	key = strings.TrimSpace(text[:idx])
+	if key == "" {
+		return "", "", fmt.Errorf("line %d: empty key", line.num)
+	}
+	return key, strings.TrimSpace(text[idx+1:]), nil
+}
+
+// parseFlow parses an inline value: a flow sequence or a scalar
+func parseFlow(text string, num int) (interface{}, error) {
+	if strings.HasPrefix(text, "[") {
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("line %d: unterminated flow sequence", num)
+		}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		items := []interface{}{}
+		if inner == "" {
+			return items, nil
+		}
+		for _, part := range splitFlow(inner) {
+			value, err := parseScalar(strings.TrimSpace(part), num)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	}
+	if strings.HasPrefix(text, "{") {
+		if text == "{}" {
+			return map[string]interface{}{}, nil
+		}
+		return nil, fmt.Errorf("line %d: flow mappings are not supported", num)
+	}
+	return parseScalar(text, num)
+}
+
+// splitFlow splits on commas that are not inside quotes
+func splitFlow(s string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseScalar converts a scalar to a string, number, bool or nil
+func parseScalar(text string, num int) (interface{}, error) {
+	if isQuoted(text) {
+		if text[len(text)-1] != text[0] || len(text) < 2 {
+			return nil, fmt.Errorf("line %d: unterminated string", num)
+		}
+		if text[0] == '"' {
+			s, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid string: %w", num, err)
+			}
+			return s, nil
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return text, nil
+}
+
+// stripComment removes a trailing "# comment" that is not inside quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func isQuoted(s string) bool {
+	return len(s) > 0 && (s[0] == '"' || s[0] == '\'')
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	input := `
+provider: bedrock   # inline comment
+model_id: "anthropic.claude-3-5-sonnet-20240620-v1:0"
+max_tokens: 4096
+stream: true
+endpoint: ~
+name: 'it''s'
+retry:
+  max_attempts: 3
+  jitter: false
+types: [feat, fix, "docs"]
+scopes:
+- api
+- cli
+rules:
+  - pattern: "^pkg/"
+    scope: pkg
+  - pattern: "^cmd/"
+    scope: cmd
+empty: []
+`
+	expected := map[string]interface{}{
+		"provider":   "bedrock",
+		"model_id":   "anthropic.claude-3-5-sonnet-20240620-v1:0",
+		"max_tokens": int64(4096),
+		"stream":     true,
+		"endpoint":   nil,
+		"name":       "it's",
+		"retry": map[string]interface{}{
+			"max_attempts": int64(3),
+			"jitter":       false,
+		},
+		"types":  []interface{}{"feat", "fix", "docs"},
+		"scopes": []interface{}{"api", "cli"},
+		"rules": []interface{}{
+			map[string]interface{}{"pattern": "^pkg/", "scope": "pkg"},
+			map[string]interface{}{"pattern": "^cmd/", "scope": "cmd"},
+		},
+		"empty": []interface{}{},
+	}
+
+	got, err := parseYAML(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, got)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "tab indentation", input: "retry:\n\tmax_attempts: 3\n"},
+		{name: "bad indentation", input: "a: 1\n  b: 2\n"},
+		{name: "missing colon", input: "just a value\n"},
+		{name: "unterminated sequence", input: "types: [feat, fix\n"},
+		{name: "top-level sequence", input: "- a\n- b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseYAML(tt.input); err == nil {
+				t.Errorf("Expected error for %q", tt.input)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package convention
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Scope strategies for the "scope_strategy" config key
+const (
+	// ScopePath uses the full path of the changed file
+	ScopePath = "path"
+	// ScopeDirectory uses the top-level directory of the changed file
+	ScopeDirectory = "directory"
+	// ScopePackage uses the package (innermost directory) of the changed file
+	ScopePackage = "package"
+	// ScopeNone omits the scope
+	ScopeNone = "none"
+)
+
+// typePattern restricts type names to what a header can carry
+var typePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// DefaultTypes is the commit type vocabulary used when none is configured
+var DefaultTypes = []string{"feat", "fix", "build", "chore", "ci", "docs", "style", "refactor", "perf", "test"}
+
+// Rules describes the commit types and scopes a repository accepts. It is
+// the single source for the prompt, the JSON schema sent to the model and
+// validation of the response.
+type Rules struct {
+	// Types lists the allowed commit types
+	Types []string `json:"types"`
+	// Scopes lists allowed scopes; when set the model must pick one of them
+	Scopes []string `json:"scopes"`
+	// ScopePatterns lists regular expressions a scope may match instead
+	ScopePatterns []string `json:"scope_patterns"`
+	// ScopeStrategy is how scopes are derived when Scopes is empty
+	ScopeStrategy string `json:"scope_strategy"`
+	// ScopeRequired makes lint reject headers without a scope; a Scopes
+	// list requires one as well
+	ScopeRequired bool `json:"scope_required"`
+	// WrapWidth is the column the body is wrapped at; 0 disables wrapping
+	WrapWidth int `json:"wrap_width"`
+	// Lint configures gudcommit lint and the commit-msg hook
+	Lint LintOptions `json:"lint"`
+}
+
+// DefaultRules returns the rules used when none are configured
+func DefaultRules() Rules {
+	return Rules{
+		Types:         append([]string(nil), DefaultTypes...),
+		ScopeStrategy: ScopePath,
+		WrapWidth:     DefaultWrapWidth,
+		Lint:          DefaultLintOptions(),
+	}
+}
+
+// Validate reports configuration mistakes such as an unknown strategy or a
+// pattern that does not compile
+func (r Rules) Validate() error {
+	if len(r.Types) == 0 {
+		return fmt.Errorf("commit.types must list at least one type")
+	}
+	for _, t := range r.Types {
+		if !typePattern.MatchString(t) {
+			return fmt.Errorf("invalid commit type %q (use lowercase letters, digits and '-')", t)
+		}
+	}
+	switch r.strategy() {
+	case ScopePath, ScopeDirectory, ScopePackage, ScopeNone:
+	default:
+		return fmt.Errorf("unknown commit.scope_strategy %q (expected %s, %s, %s or %s)",
+			r.ScopeStrategy, ScopePath, ScopeDirectory, ScopePackage, ScopeNone)
+	}
+	for _, p := range r.ScopePatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid commit.scope_patterns entry %q: %w", p, err)
+		}
+	}
+	if r.ScopeRequired && !r.UsesScope() {
+		return fmt.Errorf("commit.scope_required cannot be combined with scope_strategy %s", ScopeNone)
+	}
+	if r.WrapWidth < 0 {
+		return fmt.Errorf("commit.wrap_width must not be negative")
+	}
+	return r.Lint.validate()
+}
+
+// AllowsType reports whether t is in the type vocabulary
+func (r Rules) AllowsType(t string) bool {
+	for _, allowed := range r.Types {
+		if t == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScope reports whether scope is acceptable. Any scope is allowed
+// when neither Scopes nor ScopePatterns is set.
+func (r Rules) AllowsScope(scope string) bool {
+	if len(r.Scopes) == 0 && len(r.ScopePatterns) == 0 {
+		return true
+	}
+	for _, allowed := range r.Scopes {
+		if scope == allowed {
+			return true
+		}
+	}
+	for _, p := range r.ScopePatterns {
+		if re, err := regexp.Compile(p); err == nil && re.MatchString(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesScope reports whether commit headers carry a scope
+func (r Rules) UsesScope() bool {
+	return r.strategy() != ScopeNone || len(r.Scopes) > 0
+}
+
+// RequiresScope reports whether every header must have a scope: when
+// ScopeRequired is set or the scopes are a fixed list
+func (r Rules) RequiresScope() bool {
+	return r.UsesScope() && (r.ScopeRequired || len(r.Scopes) > 0)
+}
+
+// NormalizeScope applies the scope strategy to a scope the model returned
+// as a file path, e.g. "pkg/bedrock/client.go" becomes "pkg" for the
+// directory strategy and "bedrock" for the package strategy.
+func (r Rules) NormalizeScope(scope string) string {
+	scope = strings.TrimSpace(scope)
+	if scope == "null" || !r.UsesScope() {
+		return ""
+	}
+	if len(r.Scopes) > 0 || !strings.Contains(scope, "/") {
+		return scope
+	}
+	switch r.strategy() {
+	case ScopeDirectory:
+		return strings.SplitN(strings.TrimPrefix(scope, "/"), "/", 2)[0]
+	case ScopePackage:
+		if path.Ext(scope) != "" {
+			scope = path.Dir(scope)
+		}
+		return path.Base(scope)
+	}
+	return scope
+}
+
+// Check validates a type and scope pair against the rules
+func (r Rules) Check(typ, scope string) error {
+	if !r.AllowsType(typ) {
+		return fmt.Errorf("invalid commit type: %s", typ)
+	}
+	if scope != "" && !r.AllowsScope(scope) {
+		return fmt.Errorf("invalid commit scope: %s", scope)
+	}
+	return nil
+}
+
+// HeaderPattern matches a single-line "type(scope)!: description" header
+// using the configured types. The scope group is required unless scopes
+// are disabled; the "!" breaking marker is optional.
+func (r Rules) HeaderPattern() *regexp.Regexp {
+	types := make([]string, len(r.Types))
+	for i, t := range r.Types {
+		types[i] = regexp.QuoteMeta(t)
+	}
+	scope := `\(([^)]+)\)`
+	if !r.UsesScope() {
+		scope = `()`
+	}
+	return regexp.MustCompile(`^(` + strings.Join(types, "|") + `)` + scope + `(!)?: (.+)$`)
+}
+
+// ParseHeader parses a "type(scope)!: description" line that follows the
+// rules into a Message
+func (r Rules) ParseHeader(line string) (Message, bool) {
+	match := r.HeaderPattern().FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil || !r.AllowsScope(match[2]) {
+		return Message{}, false
+	}
+	return Message{
+		Type:        match[1],
+		Scope:       match[2],
+		Breaking:    match[3] == "!",
+		Description: strings.TrimSpace(match[4]),
+	}, true
+}
+
+// Schema returns the JSON schema the model must follow for commit messages
+func (r Rules) Schema() string {
+	var b strings.Builder
+	b.WriteString(`{
+  "type": "object",
+  "properties": {
+    "commits": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "type": {
+            "type": "string",
+            "enum": ` + jsonList(r.Types) + `
+          },
+`)
+	if r.UsesScope() {
+		b.WriteString(`          "scope": {
+            "type": "string",
+`)
+		if len(r.Scopes) > 0 {
+			b.WriteString(`            "enum": ` + jsonList(r.Scopes) + `,
+`)
+		} else if len(r.ScopePatterns) > 0 {
+			b.WriteString(`            "pattern": ` + jsonString(strings.Join(r.ScopePatterns, "|")) + `,
+`)
+		}
+		b.WriteString(`            "description": ` + jsonString(r.scopeDescription()) + `
+          },
+`)
+	}
+	required := `["type", "description"]`
+	if r.UsesScope() {
+		required = `["type", "scope", "description"]`
+	}
+	b.WriteString(`          "description": {
+            "type": "string",
+            "description": "Brief description of the change and why it was done"
+          },
+          "body": {
+            "type": "string",
+            "description": "Optional explanation of the motivation for the change, in plain sentences or a bulleted list"
+          },
+          "breaking": {
+            "type": "boolean",
+            "description": "True if the change breaks compatibility"
+          },
+          "breaking_change": {
+            "type": "string",
+            "description": "For breaking changes, what breaks and how to migrate"
+          },
+          "files": {
+            "type": "array",
+            "items": {"type": "string"},
+            "description": "Paths of the changed files this commit covers, exactly as they appear in the diff"
+          },
+          "footers": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "token": {"type": "string", "description": "Trailer name such as Refs or Co-authored-by"},
+                "value": {"type": "string"}
+              },
+              "required": ["token", "value"],
+              "additionalProperties": false
+            }
+          }
+        },
+        "required": ` + required + `,
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["commits"],
+  "additionalProperties": false
+}`)
+	return b.String()
+}
+
+// PromptRules returns the prompt lines describing types and scopes
+func (r Rules) PromptRules() string {
+	lines := []string{
+		"- Use conventional commit format: " + r.format(),
+		"- Types: " + strings.Join(r.Types, ", "),
+	}
+	switch {
+	case len(r.Scopes) > 0:
+		lines = append(lines, "- Scope must be exactly one of: "+strings.Join(r.Scopes, ", "))
+	case len(r.ScopePatterns) > 0:
+		lines = append(lines, "- Scope must match one of these regular expressions: "+strings.Join(r.ScopePatterns, ", "))
+	}
+	if !r.UsesScope() {
+		lines = append(lines, "- Do not include a scope")
+	} else if len(r.Scopes) == 0 {
+		lines = append(lines, "- "+r.scopeDescription())
+	}
+	lines = append(lines,
+		"- Keep the description to one line; explain the motivation for non-trivial changes in body and omit body for trivial ones",
+		"- Set breaking and describe the migration in breaking_change only for changes that break compatibility",
+		"- List in files every changed path the commit covers; each path belongs to exactly one commit",
+		"- Only add footers (e.g. Refs, Co-authored-by) for references stated in the diff; never invent issue numbers or authors",
+	)
+	return strings.Join(lines, "\n")
+}
+
+// format returns the header format shown to the model
+func (r Rules) format() string {
+	if r.UsesScope() {
+		return "type(scope): description"
+	}
+	return "type: description"
+}
+
+// scopeDescription explains how to choose a scope under the strategy
+func (r Rules) scopeDescription() string {
+	if len(r.Scopes) > 0 {
+		return "The component being changed, chosen from the allowed scopes"
+	}
+	switch r.strategy() {
+	case ScopeDirectory:
+		return "Scope should be the top-level directory of the changed file (e.g., 'golang', 'scripts', 'docs')"
+	case ScopePackage:
+		return "Scope should be the package or innermost directory of the changed file (e.g., 'bedrock' for 'golang/pkg/bedrock/client.go')"
+	}
+	return "Scope should be the full file path (e.g., 'golang/cmd/gudcommit/main.go', 'terraform/module/bedrock.tf')"
+}
+
+// strategy returns the scope strategy, defaulting to the file path
+func (r Rules) strategy() string {
+	if r.ScopeStrategy == "" {
+		return ScopePath
+	}
+	return strings.ToLower(r.ScopeStrategy)
+}
+
+func jsonList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = jsonString(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,159 @@
+package convention
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    Rules
+		hasError bool
+	}{
+		{name: "defaults", rules: DefaultRules()},
+		{name: "custom types", rules: Rules{Types: []string{"feat", "revert", "security", "deps"}, ScopeStrategy: ScopeNone}},
+		{name: "empty strategy", rules: Rules{Types: []string{"feat"}}},
+		{name: "no types", rules: Rules{ScopeStrategy: ScopePath}, hasError: true},
+		{name: "bad type", rules: Rules{Types: []string{"Feat!"}}, hasError: true},
+		{name: "unknown strategy", rules: Rules{Types: []string{"feat"}, ScopeStrategy: "module"}, hasError: true},
+		{name: "bad pattern", rules: Rules{Types: []string{"feat"}, ScopePatterns: []string{"("}}, hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Validate()
+			if tt.hasError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	rules := Rules{
+		Types:         []string{"feat", "fix", "security"},
+		Scopes:        []string{"api", "cli"},
+		ScopePatterns: []string{`^plugin-[a-z]+$`},
+	}
+
+	tests := []struct {
+		typ, scope string
+		hasError   bool
+	}{
+		{typ: "feat", scope: "api"},
+		{typ: "security", scope: "cli"},
+		{typ: "fix", scope: "plugin-aws"},
+		{typ: "fix", scope: ""},
+		{typ: "docs", scope: "api", hasError: true},
+		{typ: "feat", scope: "pkg/api/server.go", hasError: true},
+		{typ: "feat", scope: "plugin-AWS", hasError: true},
+	}
+	for _, tt := range tests {
+		err := rules.Check(tt.typ, tt.scope)
+		if tt.hasError && err == nil {
+			t.Errorf("Expected error for %s(%s)", tt.typ, tt.scope)
+		}
+		if !tt.hasError && err != nil {
+			t.Errorf("Unexpected error for %s(%s): %v", tt.typ, tt.scope, err)
+		}
+	}
+}
+
+func TestNormalizeScope(t *testing.T) {
+	scope := "golang/pkg/bedrock/client.go"
+	tests := []struct {
+		rules    Rules
+		scope    string
+		expected string
+	}{
+		{rules: Rules{ScopeStrategy: ScopePath}, scope: scope, expected: scope},
+		{rules: Rules{ScopeStrategy: ScopeDirectory}, scope: scope, expected: "golang"},
+		{rules: Rules{ScopeStrategy: ScopePackage}, scope: scope, expected: "bedrock"},
+		{rules: Rules{ScopeStrategy: ScopePackage}, scope: "golang/pkg/bedrock", expected: "bedrock"},
+		{rules: Rules{ScopeStrategy: ScopeNone}, scope: scope, expected: ""},
+		{rules: Rules{ScopeStrategy: ScopeDirectory}, scope: "api", expected: "api"},
+		{rules: Rules{ScopeStrategy: ScopeDirectory, Scopes: []string{"a/b"}}, scope: "a/b", expected: "a/b"},
+		{rules: Rules{}, scope: "null", expected: ""},
+	}
+	for _, tt := range tests {
+		if got := tt.rules.NormalizeScope(tt.scope); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.rules.ScopeStrategy, tt.expected, got)
+		}
+	}
+}
+
+func TestHeaderPattern(t *testing.T) {
+	rules := Rules{Types: []string{"feat", "revert", "deps"}}
+	re := rules.HeaderPattern()
+
+	for line, want := range map[string]bool{
+		"revert(api): Undo change":    true,
+		"deps(go): Bump x/net":        true,
+		"fix(api): Not a custom type": false,
+		"feat: Missing scope":         false,
+	} {
+		if got := re.MatchString(line); got != want {
+			t.Errorf("%q: expected match %v, got %v", line, want, got)
+		}
+	}
+
+	rules.ScopeStrategy = ScopeNone
+	re = rules.HeaderPattern()
+	if !re.MatchString("feat: No scope") || re.MatchString("feat(api): Scoped") {
+		t.Error("Expected scopeless headers only with the none strategy")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	rules := Rules{Types: []string{"feat", "revert"}, Scopes: []string{"api", "cli"}}
+
+	var schema struct {
+		Properties struct {
+			Commits struct {
+				Items struct {
+					Properties map[string]struct {
+						Enum []string `json:"enum"`
+					} `json:"properties"`
+					Required []string `json:"required"`
+				} `json:"items"`
+			} `json:"commits"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(rules.Schema()), &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v\n%s", err, rules.Schema())
+	}
+	items := schema.Properties.Commits.Items
+	if got := strings.Join(items.Properties["type"].Enum, ","); got != "feat,revert" {
+		t.Errorf("Expected type enum feat,revert, got %s", got)
+	}
+	if got := strings.Join(items.Properties["scope"].Enum, ","); got != "api,cli" {
+		t.Errorf("Expected scope enum api,cli, got %s", got)
+	}
+
+	none := Rules{Types: []string{"feat"}, ScopeStrategy: ScopeNone}
+	if err := json.Unmarshal([]byte(none.Schema()), &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	if strings.Contains(none.Schema(), `"scope"`) {
+		t.Error("Expected no scope property with the none strategy")
+	}
+}
+
+func TestPromptRules(t *testing.T) {
+	prompt := Rules{Types: []string{"feat", "security"}, ScopeStrategy: ScopeDirectory}.PromptRules()
+	for _, want := range []string{"type(scope): description", "Types: feat, security", "top-level directory"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, prompt)
+		}
+	}
+
+	prompt = Rules{Types: []string{"feat"}, ScopeStrategy: ScopeNone}.PromptRules()
+	if !strings.Contains(prompt, "type: description") || !strings.Contains(prompt, "Do not include a scope") {
+		t.Errorf("Unexpected prompt for none strategy:\n%s", prompt)
+	}
+}
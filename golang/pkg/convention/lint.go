@@ -0,0 +1,244 @@
+package convention
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Lint violation codes. Any of them can be turned off with commit.lint.disable.
+const (
+	LintEmpty              = "empty"
+	LintHeaderFormat       = "header-format"
+	LintHeaderMaxLength    = "header-max-length"
+	LintTypeEnum           = "type-enum"
+	LintScopeEmpty         = "scope-empty"
+	LintScopeEnum          = "scope-enum"
+	LintScopeForbidden     = "scope-forbidden"
+	LintSubjectEmpty       = "subject-empty"
+	LintSubjectCase        = "subject-case"
+	LintSubjectFullStop    = "subject-full-stop"
+	LintBodyLeadingBlank   = "body-leading-blank"
+	LintBodyMaxLineLength  = "body-max-line-length"
+	LintFooterBreakingCase = "footer-breaking-case"
+)
+
+// LintCodes lists every violation code
+var LintCodes = []string{
+	LintEmpty, LintHeaderFormat, LintHeaderMaxLength, LintTypeEnum, LintScopeEmpty, LintScopeEnum,
+	LintScopeForbidden, LintSubjectEmpty, LintSubjectCase, LintSubjectFullStop, LintBodyLeadingBlank,
+	LintBodyMaxLineLength, LintFooterBreakingCase,
+}
+
+// Values for LintOptions.SubjectCase
+const (
+	// SubjectLower requires the description to start with a lower-case letter
+	SubjectLower = "lower"
+	// SubjectSentence requires the description to start with a capital letter
+	SubjectSentence = "sentence"
+)
+
+// DefaultHeaderMaxLength is the longest header accepted by default
+const DefaultHeaderMaxLength = 100
+
+// LintOptions configures the checks made beyond the header grammar and the
+// type and scope rules. Body lines are checked against Rules.WrapWidth.
+type LintOptions struct {
+	// HeaderMaxLength is the longest header allowed; 0 disables the check
+	HeaderMaxLength int `json:"header_max_length"`
+	// SubjectCase is "lower", "sentence" or empty to allow either
+	SubjectCase string `json:"subject_case"`
+	// Disable lists violation codes to skip
+	Disable []string `json:"disable"`
+	// Suggest asks the model for a rewrite when a message fails
+	Suggest bool `json:"suggest"`
+}
+
+// DefaultLintOptions returns the lint options used when none are configured
+func DefaultLintOptions() LintOptions {
+	return LintOptions{HeaderMaxLength: DefaultHeaderMaxLength}
+}
+
+// validate reports unknown subject cases and codes
+func (o LintOptions) validate() error {
+	if o.HeaderMaxLength < 0 {
+		return fmt.Errorf("commit.lint.header_max_length must not be negative")
+	}
+	switch o.SubjectCase {
+	case "", SubjectLower, SubjectSentence:
+	default:
+		return fmt.Errorf("unknown commit.lint.subject_case %q (expected %s or %s)", o.SubjectCase, SubjectLower, SubjectSentence)
+	}
+	for _, code := range o.Disable {
+		if !contains(LintCodes, code) {
+			return fmt.Errorf("unknown commit.lint.disable code %q (expected one of %s)", code, strings.Join(LintCodes, ", "))
+		}
+	}
+	return nil
+}
+
+// Violation is one problem found in a commit message
+type Violation struct {
+	Code string
+	// Line is the 1-based line in the cleaned message
+	Line    int
+	Message string
+}
+
+// String returns "line 1: header-max-length: ..."
+func (v Violation) String() string {
+	return fmt.Sprintf("line %d: %s: %s", v.Line, v.Code, v.Message)
+}
+
+var (
+	// headerGrammar is the Conventional Commits header with any type, so that
+	// vocabulary problems get their own codes
+	headerGrammar = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*)(\(([^()]*)\))?(!)?:(?: (.*))?$`)
+	// footerLine starts a git trailer or Conventional Commits footer
+	footerLine = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z][A-Za-z0-9-]*)(: | #)`)
+	// breakingFooter matches the breaking change token in any case
+	breakingFooter = regexp.MustCompile(`(?i)^breaking[ -]change: `)
+	// ignoredHeaders are generated by git and left alone
+	ignoredHeaders = regexp.MustCompile(`^(Merge |Revert "|fixup! |squash! |amend! )`)
+)
+
+// scissors is the line below which git drops the rest of the message
+const scissors = "# ------------------------ >8 ------------------------"
+
+// CleanMessage strips what git strips from an edited message: comment
+// lines, everything below the scissors line, trailing whitespace and
+// leading and trailing blank lines
+func CleanMessage(message string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, scissors) {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return trimMessage(strings.Join(lines, "\n"))
+}
+
+// trimMessage strips trailing whitespace from each line of message and
+// leading and trailing blank lines
+func trimMessage(message string) string {
+	lines := strings.Split(message, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// LintMessage checks a commit message against the Conventional Commits
+// grammar and the rules. Comment lines are ignored, and merge, revert and
+// fixup/squash messages generated by git always pass.
+func (r Rules) LintMessage(message string) []Violation {
+	var found []Violation
+	add := func(code string, line int, format string, args ...interface{}) {
+		if !contains(r.Lint.Disable, code) {
+			found = append(found, Violation{Code: code, Line: line, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	message = CleanMessage(message)
+	if message == "" {
+		add(LintEmpty, 1, "message is empty")
+		return found
+	}
+	lines := strings.Split(message, "\n")
+	header := lines[0]
+	if ignoredHeaders.MatchString(header) {
+		return nil
+	}
+
+	if n := utf8.RuneCountInString(header); r.Lint.HeaderMaxLength > 0 && n > r.Lint.HeaderMaxLength {
+		add(LintHeaderMaxLength, 1, "header is %d characters, more than %d", n, r.Lint.HeaderMaxLength)
+	}
+	if match := headerGrammar.FindStringSubmatch(header); match == nil {
+		add(LintHeaderFormat, 1, "header must look like %q", r.format())
+	} else {
+		r.lintHeader(match[1], match[2] != "", match[3], match[5], add)
+	}
+
+	if len(lines) > 1 && lines[1] != "" {
+		add(LintBodyLeadingBlank, 2, "body must be separated from the header by a blank line")
+	}
+
+	footers := footerStart(lines)
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		if breakingFooter.MatchString(line) && !strings.HasPrefix(line, BreakingChangeToken+": ") && !strings.HasPrefix(line, "BREAKING-CHANGE: ") {
+			add(LintFooterBreakingCase, i+1, "%s must be upper case", BreakingChangeToken)
+		}
+		// Footers, indented blocks and unbreakable lines such as URLs are
+		// exempt, as Wrap leaves them alone too
+		if i >= footers || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || !strings.Contains(line, " ") {
+			continue
+		}
+		if n := utf8.RuneCountInString(line); r.WrapWidth > 0 && n > r.WrapWidth {
+			add(LintBodyMaxLineLength, i+1, "line is %d characters, more than %d", n, r.WrapWidth)
+		}
+	}
+	return found
+}
+
+// lintHeader checks the parts of a header that matched the grammar
+func (r Rules) lintHeader(typ string, hasScope bool, scope, description string, add func(code string, line int, format string, args ...interface{})) {
+	if !r.AllowsType(typ) {
+		add(LintTypeEnum, 1, "type %q is not one of: %s", typ, strings.Join(r.Types, ", "))
+	}
+	switch {
+	case !r.UsesScope() && hasScope:
+		add(LintScopeForbidden, 1, "scope must be omitted")
+	case (hasScope || r.RequiresScope()) && strings.TrimSpace(scope) == "":
+		add(LintScopeEmpty, 1, "scope is required")
+	case hasScope && !r.AllowsScope(scope):
+		add(LintScopeEnum, 1, "scope %q is not allowed", scope)
+	}
+
+	description = strings.TrimSpace(description)
+	if description == "" {
+		add(LintSubjectEmpty, 1, "description is empty")
+		return
+	}
+	first, _ := utf8.DecodeRuneInString(description)
+	switch {
+	case r.Lint.SubjectCase == SubjectLower && unicode.IsUpper(first):
+		add(LintSubjectCase, 1, "description must start with a lower-case letter")
+	case r.Lint.SubjectCase == SubjectSentence && unicode.IsLower(first):
+		add(LintSubjectCase, 1, "description must start with a capital letter")
+	}
+	if strings.HasSuffix(description, ".") {
+		add(LintSubjectFullStop, 1, "description must not end with a period")
+	}
+}
+
+// footerStart returns the index of the first line of the footer paragraph,
+// or len(lines) when the message has no footers
+func footerStart(lines []string) int {
+	start := len(lines)
+	for i := len(lines) - 1; i > 1; i-- {
+		if lines[i] == "" {
+			break
+		}
+		start = i
+	}
+	if start < len(lines) && lines[start-1] == "" && footerLine.MatchString(lines[start]) {
+		return start
+	}
+	return len(lines)
+}
+
+func contains(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
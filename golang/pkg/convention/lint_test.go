@@ -0,0 +1,140 @@
+package convention
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func codes(violations []Violation) []string {
+	var out []string
+	for _, v := range violations {
+		out = append(out, v.Code)
+	}
+	return out
+}
+
+func TestLintMessage(t *testing.T) {
+	rules := DefaultRules()
+	rules.Scopes = []string{"api", "cli"}
+
+	tests := []struct {
+		name     string
+		message  string
+		expected []string
+	}{
+		{name: "valid", message: "feat(api): add routes"},
+		{name: "valid with body and footers", message: "fix(cli)!: drop --legacy flag\n\nThe flag has been deprecated for a year.\n\nBREAKING CHANGE: scripts using --legacy must switch to --mode\nRefs: #12"},
+		{name: "comments and scissors", message: "feat(api): add routes\n# Please enter the commit message\n# ------------------------ >8 ------------------------\ndiff --git a/x b/x"},
+		{name: "merge", message: "Merge branch 'main' into feature"},
+		{name: "fixup", message: "fixup! feat(api): add routes"},
+		{name: "empty", message: "\n# only comments\n", expected: []string{LintEmpty}},
+		{name: "not conventional", message: "Added some routes", expected: []string{LintHeaderFormat}},
+		{name: "missing space", message: "feat(api):add routes", expected: []string{LintHeaderFormat}},
+		{name: "unknown type", message: "feature(api): add routes", expected: []string{LintTypeEnum}},
+		{name: "missing scope", message: "feat: add routes", expected: []string{LintScopeEmpty}},
+		{name: "empty scope", message: "feat(): add routes", expected: []string{LintScopeEmpty}},
+		{name: "unknown scope", message: "feat(web): add routes", expected: []string{LintScopeEnum}},
+		{name: "empty description", message: "feat(api): ", expected: []string{LintSubjectEmpty}},
+		{name: "trailing period", message: "feat(api): add routes.", expected: []string{LintSubjectFullStop}},
+		{name: "long header", message: "feat(api): " + strings.Repeat("x", 95), expected: []string{LintHeaderMaxLength}},
+		{name: "no blank line", message: "feat(api): add routes\nmore detail", expected: []string{LintBodyLeadingBlank}},
+		{
+			name:     "long body line",
+			message:  "feat(api): add routes\n\n" + strings.Repeat("word ", 20) + "\n\n    " + strings.Repeat("code ", 20) + "\nhttps://example.com/" + strings.Repeat("x", 80),
+			expected: []string{LintBodyMaxLineLength},
+		},
+		{name: "long footer", message: "feat(api): add routes\n\nCo-authored-by: " + strings.Repeat("Name ", 20)},
+		{name: "breaking case", message: "feat(api): add routes\n\nBreaking change: removes v1", expected: []string{LintFooterBreakingCase}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codes(rules.LintMessage(tt.message)); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v (%v)", tt.expected, got, rules.LintMessage(tt.message))
+			}
+		})
+	}
+}
+
+func TestLintMessageScopeOptional(t *testing.T) {
+	// The default rules suggest a scope but accept a header without one
+	rules := DefaultRules()
+	for _, message := range []string{"docs: fix typo", "feat(api): add routes"} {
+		if findings := rules.LintMessage(message); len(findings) > 0 {
+			t.Errorf("Expected %q to pass, got %v", message, findings)
+		}
+	}
+	if got := codes(rules.LintMessage("docs(): fix typo")); !reflect.DeepEqual(got, []string{LintScopeEmpty}) {
+		t.Errorf("Expected an empty scope to fail, got %v", got)
+	}
+
+	rules.ScopeRequired = true
+	if got := codes(rules.LintMessage("docs: fix typo")); !reflect.DeepEqual(got, []string{LintScopeEmpty}) {
+		t.Errorf("Expected a missing scope to fail with scope_required, got %v", got)
+	}
+
+	rules.ScopeStrategy = ScopeNone
+	if err := rules.Validate(); err == nil {
+		t.Error("Expected an error for scope_required without scopes")
+	}
+}
+
+func TestLintMessageOptions(t *testing.T) {
+	rules := Rules{Types: []string{"feat"}, ScopeStrategy: ScopeNone}
+
+	if got := codes(rules.LintMessage("feat(api): Add routes")); !reflect.DeepEqual(got, []string{LintScopeForbidden}) {
+		t.Errorf("Expected scope to be forbidden, got %v", got)
+	}
+
+	rules.Lint = LintOptions{SubjectCase: SubjectLower}
+	if got := codes(rules.LintMessage("feat: Add routes")); !reflect.DeepEqual(got, []string{LintSubjectCase}) {
+		t.Errorf("Expected lower case to be required, got %v", got)
+	}
+	rules.Lint = LintOptions{SubjectCase: SubjectSentence}
+	if got := codes(rules.LintMessage("feat: add routes")); !reflect.DeepEqual(got, []string{LintSubjectCase}) {
+		t.Errorf("Expected sentence case to be required, got %v", got)
+	}
+
+	rules.Lint.Disable = []string{LintSubjectCase, LintSubjectFullStop}
+	if got := rules.LintMessage("feat: add routes."); len(got) != 0 {
+		t.Errorf("Expected disabled codes to be skipped, got %v", got)
+	}
+
+	v := Violation{Code: LintTypeEnum, Line: 1, Message: "bad"}
+	if v.String() != "line 1: type-enum: bad" {
+		t.Errorf("Unexpected string %q", v.String())
+	}
+}
+
+func TestLintRenderedMessages(t *testing.T) {
+	rules := DefaultRules()
+	m := Message{
+		Type:           "feat",
+		Scope:          "pkg/api/server.go",
+		Description:    "add graceful shutdown",
+		Body:           strings.Repeat("The server now drains open connections before exiting. ", 4) + "\n\n- first item that is long enough to need wrapping at the configured width of the body",
+		Breaking:       true,
+		BreakingChange: "Shutdown now waits up to thirty seconds, so deployment scripts that kill the process immediately should send SIGTERM first.",
+		Footers:        []Footer{{Token: "Refs", Value: "#42"}},
+	}
+	if got := rules.LintMessage(m.Render(rules.WrapWidth)); len(got) != 0 {
+		t.Errorf("Expected a rendered message to pass, got %v", got)
+	}
+}
+
+func TestLintOptionsValidate(t *testing.T) {
+	for _, opts := range []LintOptions{{SubjectCase: "title"}, {Disable: []string{"nope"}}, {HeaderMaxLength: -1}} {
+		rules := DefaultRules()
+		rules.Lint = opts
+		if err := rules.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", opts)
+		}
+	}
+}
+
+func TestCleanMessage(t *testing.T) {
+	got := CleanMessage("\n\nfeat: x  \n# comment\n\nbody\n\n")
+	if got != "feat: x\n\nbody" {
+		t.Errorf("Unexpected cleaned message %q", got)
+	}
+}
@@ -0,0 +1,224 @@
+package convention
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultWrapWidth is the column commit bodies are wrapped at by default
+const DefaultWrapWidth = 72
+
+// BreakingChangeToken is the footer token that marks an incompatible change
+const BreakingChangeToken = "BREAKING CHANGE"
+
+// footerToken matches a git trailer style token such as Refs or Co-authored-by
+var footerToken = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+
+// listItem matches the marker of a bulleted or numbered list item
+var listItem = regexp.MustCompile(`^([-*+]|\d+[.)]) `)
+
+// Footer is a "Token: value" trailer at the end of a commit message
+type Footer struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+// Message is a structured Conventional Commits 1.0 message
+type Message struct {
+	Type        string `json:"type"`
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+	// Body explains the motivation for the change
+	Body string `json:"body,omitempty"`
+	// Breaking adds the "!" marker to the header
+	Breaking bool `json:"breaking,omitempty"`
+	// BreakingChange describes the incompatibility and how to migrate
+	BreakingChange string   `json:"breaking_change,omitempty"`
+	Footers        []Footer `json:"footers,omitempty"`
+	// Files lists the paths the commit covers when splitting staged changes
+	Files []string `json:"files,omitempty"`
+}
+
+// Header returns the "type(scope)!: description" subject line
+func (m Message) Header() string {
+	var b strings.Builder
+	b.WriteString(m.Type)
+	if m.Scope != "" {
+		b.WriteString("(" + m.Scope + ")")
+	}
+	if m.IsBreaking() {
+		b.WriteString("!")
+	}
+	b.WriteString(": " + m.Description)
+	return b.String()
+}
+
+// IsBreaking reports whether the message describes an incompatible change
+func (m Message) IsBreaking() bool {
+	if m.Breaking || strings.TrimSpace(m.BreakingChange) != "" {
+		return true
+	}
+	for _, f := range m.Footers {
+		if isBreakingToken(f.Token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render formats the message with a blank line after the header, the body
+// wrapped at width columns (no wrapping when width <= 0) and footers last.
+func (m Message) Render(width int) string {
+	parts := []string{m.Header()}
+
+	if body := strings.TrimSpace(m.Body); body != "" {
+		parts = append(parts, Wrap(body, width))
+	}
+
+	var footers []string
+	if text := strings.TrimSpace(m.BreakingChange); text != "" {
+		footers = append(footers, BreakingChangeToken+": "+text)
+	}
+	for _, f := range m.Footers {
+		token, value := normalizeToken(f.Token), strings.TrimSpace(f.Value)
+		if token == "" || value == "" {
+			continue
+		}
+		if token == BreakingChangeToken && m.BreakingChange != "" {
+			continue
+		}
+		footers = append(footers, token+": "+value)
+	}
+	if len(footers) > 0 {
+		parts = append(parts, strings.Join(footers, "\n"))
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// ParseMessage parses a commit message of any type that follows the
+// Conventional Commits grammar, reading the body and footers. It reports
+// false for other messages, including merges and reverts generated by git.
+// The message is taken as git stored it, so lines starting with "#" are
+// kept; pass an edited message through CleanMessage first.
+func ParseMessage(message string) (Message, bool) {
+	message = trimMessage(message)
+	lines := strings.Split(message, "\n")
+	if ignoredHeaders.MatchString(lines[0]) {
+		return Message{}, false
+	}
+	match := headerGrammar.FindStringSubmatch(lines[0])
+	if match == nil || strings.TrimSpace(match[5]) == "" {
+		return Message{}, false
+	}
+	m := Message{
+		Type:        strings.ToLower(match[1]),
+		Scope:       strings.TrimSpace(match[3]),
+		Breaking:    match[4] == "!",
+		Description: strings.TrimSpace(match[5]),
+	}
+
+	footers := footerStart(lines)
+	if footers > 1 {
+		m.Body = strings.TrimSpace(strings.Join(lines[1:footers], "\n"))
+	}
+	inBreaking := false
+	for i := footers; i < len(lines); i++ {
+		match := footerLine.FindStringSubmatch(lines[i])
+		switch {
+		case match != nil && isBreakingToken(match[1]):
+			m.BreakingChange = strings.TrimSpace(lines[i][len(match[0]):])
+			inBreaking = true
+		case match != nil:
+			m.Footers = append(m.Footers, Footer{Token: match[1], Value: strings.TrimSpace(lines[i][len(match[0]):])})
+			inBreaking = false
+		// A footer value may continue on the following lines
+		case inBreaking:
+			m.BreakingChange += "\n" + lines[i]
+		case len(m.Footers) > 0:
+			m.Footers[len(m.Footers)-1].Value += "\n" + lines[i]
+		}
+	}
+	return m, true
+}
+
+// Wrap reflows text to width columns. Paragraphs and list items are wrapped
+// separately, with list continuation lines indented under the item text;
+// indented lines such as code are kept as they are.
+func Wrap(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	var out []string
+	var para []string
+	indent := ""
+	flush := func() {
+		if len(para) > 0 {
+			out = append(out, wrapParagraph(strings.Join(para, " "), width, indent)...)
+			para, indent = nil, ""
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+			out = append(out, "")
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			flush()
+			out = append(out, strings.TrimRight(line, " \t"))
+		case listItem.MatchString(trimmed):
+			flush()
+			para = []string{trimmed}
+			indent = strings.Repeat(" ", len(listItem.FindString(trimmed)))
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+
+	return strings.Join(out, "\n")
+}
+
+// wrapParagraph greedily fills lines up to width; words longer than width
+// get a line of their own
+func wrapParagraph(text string, width int, indent string) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = indent + word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// normalizeToken returns the footer token in trailer form, or "" if it
+// cannot be one. Spaces become hyphens except in BREAKING CHANGE.
+func normalizeToken(token string) string {
+	token = strings.TrimSuffix(strings.TrimSpace(token), ":")
+	if isBreakingToken(token) {
+		return BreakingChangeToken
+	}
+	token = strings.Join(strings.Fields(token), "-")
+	if !footerToken.MatchString(token) {
+		return ""
+	}
+	return token
+}
+
+func isBreakingToken(token string) bool {
+	token = strings.TrimSuffix(strings.TrimSpace(token), ":")
+	return token == BreakingChangeToken || token == "BREAKING-CHANGE"
+}
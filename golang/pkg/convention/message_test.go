@@ -0,0 +1,175 @@
+package convention
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMessageRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  Message
+		width    int
+		expected string
+	}{
+		{
+			name:     "Subject only",
+			message:  Message{Type: "fix", Scope: "api", Description: "Handle empty body"},
+			width:    72,
+			expected: "fix(api): Handle empty body",
+		},
+		{
+			name:     "No scope",
+			message:  Message{Type: "docs", Description: "Fix typo"},
+			width:    72,
+			expected: "docs: Fix typo",
+		},
+		{
+			name: "Body wrapped and footers",
+			message: Message{
+				Type:        "feat",
+				Scope:       "cli",
+				Description: "Add --json output",
+				Body:        "Wrapper scripts need machine readable output instead of scraping the terminal text.",
+				Footers: []Footer{
+					{Token: "Refs", Value: "#42"},
+					{Token: "Co-authored-by", Value: "Sam Doe <sam@example.com>"},
+				},
+			},
+			width: 40,
+			expected: `feat(cli): Add --json output
+
+Wrapper scripts need machine readable
+output instead of scraping the terminal
+text.
+
+Refs: #42
+Co-authored-by: Sam Doe <sam@example.com>`,
+		},
+		{
+			name: "Breaking change",
+			message: Message{
+				Type:           "refactor",
+				Scope:          "config",
+				Description:    "Rename timeout key",
+				BreakingChange: "timeout is now timeout_seconds.",
+			},
+			width: 72,
+			expected: `refactor(config)!: Rename timeout key
+
+BREAKING CHANGE: timeout is now timeout_seconds.`,
+		},
+		{
+			name: "Breaking footer and invalid token",
+			message: Message{
+				Type:        "feat",
+				Description: "Drop v1 API",
+				Footers: []Footer{
+					{Token: "BREAKING CHANGE", Value: "v1 endpoints are gone"},
+					{Token: "Reviewed by", Value: "Alex"},
+					{Token: "not a token!", Value: "dropped"},
+				},
+			},
+			width: 72,
+			expected: `feat!: Drop v1 API
+
+BREAKING CHANGE: v1 endpoints are gone
+Reviewed-by: Alex`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.message.Render(tt.width); got != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	input := `First paragraph that is long enough to wrap around.
+
+- A list item that also needs wrapping at width
+- Short item
+    indented code stays as it is even when it is long
+Trailing paragraph.`
+
+	expected := `First paragraph that is long
+enough to wrap around.
+
+- A list item that also needs
+  wrapping at width
+- Short item
+    indented code stays as it is even when it is long
+Trailing paragraph.`
+
+	if got := Wrap(input, 30); got != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, got)
+	}
+	if got := Wrap(input, 0); got != input {
+		t.Errorf("Expected width 0 to leave text unchanged, got:\n%s", got)
+	}
+
+	long := strings.Repeat("x", 50)
+	if got := Wrap("a "+long+" b", 20); got != "a\n"+long+"\nb" {
+		t.Errorf("Expected long word on its own line, got %q", got)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected Message
+		ok       bool
+	}{
+		{
+			name:     "Header only",
+			message:  "feat(api): add retries\n",
+			expected: Message{Type: "feat", Scope: "api", Description: "add retries"},
+			ok:       true,
+		},
+		{
+			name:     "Any type, no scope, breaking marker",
+			message:  "Deploy!: move to the new cluster",
+			expected: Message{Type: "deploy", Breaking: true, Description: "move to the new cluster"},
+			ok:       true,
+		},
+		{
+			name:    "Body and footers",
+			message: "fix: handle empty bodies\n\nThe parser crashed.\n\nIt no longer does.\n\nRefs: #12\nBREAKING CHANGE: empty bodies\n  are now errors\nReviewed-by: Ann\n",
+			expected: Message{
+				Type:           "fix",
+				Description:    "handle empty bodies",
+				Body:           "The parser crashed.\n\nIt no longer does.",
+				BreakingChange: "empty bodies\n  are now errors",
+				Footers:        []Footer{{Token: "Refs", Value: "#12"}, {Token: "Reviewed-by", Value: "Ann"}},
+			},
+			ok: true,
+		},
+		{
+			name:     "Lines starting with # are content",
+			message:  "fix: drop the upload workaround\n\n#123 was fixed upstream.\n",
+			expected: Message{Type: "fix", Description: "drop the upload workaround", Body: "#123 was fixed upstream."},
+			ok:       true,
+		},
+		{name: "Not conventional", message: "Update the README"},
+		{name: "Empty description", message: "feat: "},
+		{name: "Merge", message: "Merge branch 'main' into feature"},
+		{name: "Revert", message: "Revert \"feat: add retries\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseMessage(tt.message)
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v %v, got %+v %v", tt.expected, tt.ok, got, ok)
+			}
+			if ok && !got.IsBreaking() != (tt.expected.BreakingChange == "" && !tt.expected.Breaking) {
+				t.Errorf("Unexpected IsBreaking for %+v", got)
+			}
+		})
+	}
+}
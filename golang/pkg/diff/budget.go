@@ -0,0 +1,280 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Defaults for Options
+const (
+	DefaultMaxTokens     = 30000
+	DefaultChunkTokens   = 12000
+	DefaultHardCapTokens = 500000
+	DefaultParallelism   = 4
+)
+
+// charsPerToken is a deliberately low estimate; code and diffs tokenize
+// more densely than prose
+const charsPerToken = 3
+
+// Options controls how much of a diff is sent to the model
+type Options struct {
+	// MaxTokens is the largest diff sent as-is; bigger diffs are chunked and
+	// summarised first
+	MaxTokens int `json:"max_tokens"`
+	// ChunkTokens is the size of each chunk sent for summarising
+	ChunkTokens int `json:"chunk_tokens"`
+	// HardCapTokens refuses diffs above this size outright; 0 disables it
+	HardCapTokens int `json:"hard_cap_tokens"`
+	// Parallelism is the number of chunks summarised at once
+	Parallelism int `json:"parallelism"`
+	// Exclude lists gitignore-style patterns for files whose diff is
+	// replaced by a one-line summary; "!pattern" re-includes files
+	Exclude []string `json:"exclude"`
+	// BuiltinExcludes applies the default lockfile, vendor, minified and
+	// generated file patterns
+	BuiltinExcludes bool `json:"builtin_excludes"`
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{
+		MaxTokens:       DefaultMaxTokens,
+		ChunkTokens:     DefaultChunkTokens,
+		HardCapTokens:   DefaultHardCapTokens,
+		Parallelism:     DefaultParallelism,
+		BuiltinExcludes: true,
+	}
+}
+
+// OverCapError is returned when a diff exceeds the hard cap
+type OverCapError struct {
+	Tokens int
+	Cap    int
+}
+
+// Error explains the cap and how to get under it
+func (e *OverCapError) Error() string {
+	return fmt.Sprintf("diff is about %d tokens, over the hard cap of %d (diff.hard_cap_tokens); "+
+		"commit in smaller pieces, exclude generated files, or raise the cap", e.Tokens, e.Cap)
+}
+
+// GenerateFunc sends a prompt to the model and returns its response
+type GenerateFunc func(ctx context.Context, prompt string) (string, error)
+
+// Result is a diff prepared for the final prompt
+type Result struct {
+	// Text is the diff itself, or the chunk summaries when Summarized
+	Text       string
+	Summarized bool
+	// Tokens is the estimated size of the original diff
+	Tokens int
+	// Chunks is the number of chunks that were summarised
+	Chunks int
+}
+
+// Subject names what Text holds, for use in prompts
+func (r Result) Subject() string {
+	if r.Summarized {
+		return "summaries of a git diff that was too large to send in full"
+	}
+	return "git diff"
+}
+
+// Block wraps Text in the tag the prompt refers to
+func (r Result) Block() string {
+	if r.Summarized {
+		return "<diff_summaries>\n" + r.Text + "</diff_summaries>"
+	}
+	return "<git_diff>" + r.Text + "</git_diff>"
+}
+
+// EstimateTokens approximates the number of model tokens in s
+func EstimateTokens(s string) int {
+	return (len(s) + charsPerToken - 1) / charsPerToken
+}
+
+// Fit returns text unchanged when it is within opts.MaxTokens. Larger diffs
+// are split per file and hunk, the chunks are summarised in parallel with
+// generate, and the summaries are returned instead. progress, if not nil, is
+// called with done == 0 before summarising starts and after each chunk.
+func Fit(ctx context.Context, text string, opts Options, generate GenerateFunc, progress func(done, total int)) (Result, error) {
+	opts = opts.withDefaults()
+	tokens := EstimateTokens(text)
+	if opts.HardCapTokens > 0 && tokens > opts.HardCapTokens {
+		return Result{Tokens: tokens}, &OverCapError{Tokens: tokens, Cap: opts.HardCapTokens}
+	}
+	if tokens <= opts.MaxTokens {
+		return Result{Text: text, Tokens: tokens}, nil
+	}
+
+	chunks := Chunk(Parse(text), opts.ChunkTokens)
+	if progress != nil {
+		progress(0, len(chunks))
+	}
+	summaries, err := Summarize(ctx, chunks, opts.Parallelism, func(ctx context.Context, i int, chunk string) (string, error) {
+		return generate(ctx, SummaryPrompt(chunk, i+1, len(chunks)))
+	}, progress)
+	if err != nil {
+		return Result{Tokens: tokens}, err
+	}
+
+	var b strings.Builder
+	for i, s := range summaries {
+		fmt.Fprintf(&b, "<summary part=\"%d\">\n%s\n</summary>\n", i+1, strings.TrimSpace(s))
+	}
+	return Result{Text: b.String(), Summarized: true, Tokens: tokens, Chunks: len(chunks)}, nil
+}
+
+// SummaryPrompt asks the model to summarise one chunk of a larger diff
+func SummaryPrompt(chunk string, part, total int) string {
+	return fmt.Sprintf(`The following is part %d of %d of a large git diff. Summarise it so that a commit message or changelog can later be written from the summaries alone.
+
+<git_diff>%s</git_diff>
+
+Rules:
+- For each file, give its path and a concise bullet list of what changed and, where evident, why
+- Mention added or removed public functions, types, flags and config keys by name
+- Call out breaking changes explicitly
+- Do not include code or any explanatory text beyond the summary`, part, total, chunk)
+}
+
+// Chunk packs files into chunks of at most maxTokens. Files that do not fit
+// in one chunk are split between hunks, and a single hunk that is still too
+// large is truncated with a marker.
+func Chunk(files []File, maxTokens int) []string {
+	if maxTokens <= 0 {
+		maxTokens = DefaultChunkTokens
+	}
+
+	var chunks []string
+	var cur strings.Builder
+	add := func(piece string) {
+		if cur.Len() > 0 && EstimateTokens(cur.String()+piece) > maxTokens {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(piece)
+	}
+
+	for _, f := range files {
+		if EstimateTokens(f.String()) <= maxTokens {
+			add(f.String())
+			continue
+		}
+		// Repeat the file header before each hunk so every chunk names its file
+		for _, h := range f.Hunks {
+			piece := f.Header + h
+			if EstimateTokens(piece) > maxTokens {
+				piece = truncate(piece, maxTokens)
+			}
+			add(piece)
+		}
+		if len(f.Hunks) == 0 {
+			add(truncate(f.Header, maxTokens))
+		}
+	}
+	if cur.Len() > 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
+
+// truncate cuts text to whole lines within maxTokens and notes how many
+// lines were dropped
+func truncate(text string, maxTokens int) string {
+	lines := strings.SplitAfter(text, "\n")
+	limit := maxTokens*charsPerToken - 64
+	var b strings.Builder
+	kept := 0
+	for _, line := range lines {
+		if b.Len()+len(line) > limit {
+			break
+		}
+		b.WriteString(line)
+		kept++
+	}
+	if dropped := len(lines) - kept; dropped > 0 {
+		fmt.Fprintf(&b, "... [%d lines truncated]\n", dropped)
+	}
+	return b.String()
+}
+
+// Summarize calls fn for every chunk with at most parallelism calls in
+// flight and returns the results in chunk order. The first error cancels
+// the remaining calls.
+func Summarize(ctx context.Context, chunks []string, parallelism int, fn func(ctx context.Context, i int, chunk string) (string, error), progress func(done, total int)) ([]string, error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]string, len(chunks))
+	slots := make(chan struct{}, parallelism)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		done     int
+	)
+
+	for i, chunk := range chunks {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			summary, err := fn(ctx, i, chunk)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to summarise part %d of %d: %w", i+1, len(chunks), err)
+					cancel()
+				}
+				return
+			}
+			results[i] = summary
+			done++
+			if progress != nil {
+				progress(done, len(chunks))
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// withDefaults fills unset options
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.MaxTokens <= 0 {
+		o.MaxTokens = d.MaxTokens
+	}
+	if o.ChunkTokens <= 0 {
+		o.ChunkTokens = d.ChunkTokens
+	}
+	if o.Parallelism <= 0 {
+		o.Parallelism = d.Parallelism
+	}
+	return o
+}
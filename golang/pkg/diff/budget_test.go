@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// bigDiff builds a diff of n files with one hunk of lines lines each
+func bigDiff(n, lines int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "diff --git a/f%d.go b/f%d.go\n--- a/f%d.go\n+++ b/f%d.go\n@@ -1 +1,%d @@\n", i, i, i, i, lines)
+		for j := 0; j < lines; j++ {
+			fmt.Fprintf(&b, "+line %d of file %d\n", j, i)
+		}
+	}
+	return b.String()
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens(""); got != 0 {
+		t.Errorf("Expected 0, got %d", got)
+	}
+	if got := EstimateTokens(strings.Repeat("x", 30)); got != 10 {
+		t.Errorf("Expected 10, got %d", got)
+	}
+	if got := EstimateTokens("abcd"); got != 2 {
+		t.Errorf("Expected rounding up to 2, got %d", got)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	files := Parse(bigDiff(6, 20))
+	chunks := Chunk(files, 400)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+	total := ""
+	for _, c := range chunks {
+		if EstimateTokens(c) > 400 {
+			t.Errorf("Chunk of %d tokens exceeds budget", EstimateTokens(c))
+		}
+		total += c
+	}
+	if total != bigDiff(6, 20) {
+		t.Error("Expected chunks to cover the whole diff when files fit")
+	}
+
+	// A single file larger than a chunk is split by hunk and truncated
+	huge := Parse(bigDiff(1, 500))
+	chunks = Chunk(huge, 200)
+	if len(chunks) != 1 || !strings.Contains(chunks[0], "lines truncated]") {
+		t.Errorf("Expected one truncated chunk, got %d", len(chunks))
+	}
+	if !strings.HasPrefix(chunks[0], "diff --git a/f0.go") {
+		t.Error("Expected truncated chunk to keep its file header")
+	}
+}
+
+func TestFitWithinBudget(t *testing.T) {
+	text := bigDiff(1, 5)
+	result, err := Fit(context.Background(), text, DefaultOptions(), func(context.Context, string) (string, error) {
+		t.Fatal("Expected no summarising for a small diff")
+		return "", nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Summarized || result.Text != text {
+		t.Error("Expected the diff to be returned unchanged")
+	}
+	if !strings.HasPrefix(result.Block(), "<git_diff>") {
+		t.Errorf("Unexpected block: %s", result.Block())
+	}
+}
+
+func TestFitSummarizes(t *testing.T) {
+	text := bigDiff(8, 30)
+	opts := Options{MaxTokens: 500, ChunkTokens: 400, HardCapTokens: 100000, Parallelism: 3}
+
+	var calls int32
+	var progress []int
+	result, err := Fit(context.Background(), text, opts, func(_ context.Context, prompt string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		if !strings.Contains(prompt, "<git_diff>diff --git") {
+			t.Errorf("Expected chunk in prompt, got %q", prompt[:80])
+		}
+		return "summary", nil
+	}, func(done, total int) {
+		progress = append(progress, done)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.Summarized || result.Chunks != int(calls) || result.Chunks < 2 {
+		t.Errorf("Expected one summary per chunk, got %d chunks and %d calls", result.Chunks, calls)
+	}
+	if strings.Count(result.Text, "<summary part=") != result.Chunks {
+		t.Errorf("Expected %d summaries in text, got:\n%s", result.Chunks, result.Text)
+	}
+	if len(progress) != result.Chunks+1 || progress[0] != 0 || progress[len(progress)-1] != result.Chunks {
+		t.Errorf("Unexpected progress calls: %v", progress)
+	}
+	if !strings.Contains(result.Subject(), "summaries") || !strings.HasPrefix(result.Block(), "<diff_summaries>") {
+		t.Errorf("Unexpected subject or block for summaries")
+	}
+}
+
+func TestFitHardCap(t *testing.T) {
+	text := bigDiff(10, 50)
+	_, err := Fit(context.Background(), text, Options{HardCapTokens: 100}, nil, nil)
+
+	var overCap *OverCapError
+	if !errors.As(err, &overCap) {
+		t.Fatalf("Expected OverCapError, got %v", err)
+	}
+	if overCap.Cap != 100 || overCap.Tokens != EstimateTokens(text) {
+		t.Errorf("Unexpected error values: %+v", overCap)
+	}
+	if !strings.Contains(err.Error(), "hard_cap_tokens") {
+		t.Errorf("Expected message to name the config key, got %q", err.Error())
+	}
+}
+
+func TestSummarizeError(t *testing.T) {
+	chunks := []string{"a", "b", "c", "d"}
+	boom := errors.New("boom")
+
+	_, err := Summarize(context.Background(), chunks, 2, func(ctx context.Context, i int, chunk string) (string, error) {
+		if chunk == "b" {
+			return "", boom
+		}
+		<-ctx.Done()
+		return "", ctx.Err()
+	}, nil)
+	if !errors.Is(err, boom) {
+		t.Fatalf("Expected the first error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "part 2 of 4") {
+		t.Errorf("Expected error to name the failed part, got %q", err.Error())
+	}
+}
+
+func TestSummarizeOrder(t *testing.T) {
+	chunks := []string{"a", "b", "c", "d", "e"}
+	results, err := Summarize(context.Background(), chunks, 2, func(_ context.Context, i int, chunk string) (string, error) {
+		return strings.ToUpper(chunk), nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(results, "") != "ABCDE" {
+		t.Errorf("Expected results in chunk order, got %v", results)
+	}
+}
@@ -0,0 +1,96 @@
+package diff
+
+import (
+	"strings"
+)
+
+// EmptyTree is git's well-known empty tree, diffed against for changes from
+// before the first commit
+const EmptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// File is the part of a unified git diff that belongs to one file
+type File struct {
+	// Path is the file's path after the change (before it, for deletions)
+	Path string
+	// Header holds the "diff --git" line and extended headers up to the
+	// first hunk
+	Header string
+	// Hunks holds each "@@" section including its header line
+	Hunks []string
+}
+
+// String returns the file's diff text
+func (f File) String() string {
+	return f.Header + strings.Join(f.Hunks, "")
+}
+
+// Parse splits the output of git diff into files. Text before the first
+// "diff --git" line is ignored.
+func Parse(text string) []File {
+	var files []File
+	var cur *File
+	var hunk strings.Builder
+
+	flushHunk := func() {
+		if cur != nil && hunk.Len() > 0 {
+			cur.Hunks = append(cur.Hunks, hunk.String())
+			hunk.Reset()
+		}
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flushHunk()
+			files = append(files, File{Path: pathFromGitLine(line), Header: line})
+			cur = &files[len(files)-1]
+		case cur == nil:
+			continue
+		case strings.HasPrefix(line, "@@"):
+			flushHunk()
+			hunk.WriteString(line)
+		case hunk.Len() > 0:
+			hunk.WriteString(line)
+		default:
+			cur.Header += line
+			if p := strings.TrimRight(line, "\n"); strings.HasPrefix(p, "+++ ") && p != "+++ /dev/null" {
+				cur.Path = stripPrefix(unquote(strings.TrimPrefix(p, "+++ ")))
+			}
+		}
+	}
+	flushHunk()
+
+	return files
+}
+
+// pathFromGitLine reads the new path from "diff --git a/x b/x"
+func pathFromGitLine(line string) string {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "diff --git "))
+	if strings.HasPrefix(rest, `"`) {
+		// Quoted paths: "a/x y" "b/x y"
+		if i := strings.LastIndex(rest, ` "`); i >= 0 {
+			return stripPrefix(unquote(rest[i+1:]))
+		}
+	}
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return rest[i+3:]
+	}
+	return rest
+}
+
+func stripPrefix(p string) string {
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		return p[2:]
+	}
+	return p
+}
+
+func unquote(p string) string {
+	if len(p) >= 2 && p[0] == '"' && p[len(p)-1] == '"' {
+		return p[1 : len(p)-1]
+	}
+	return p
+}
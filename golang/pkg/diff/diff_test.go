@@ -0,0 +1,73 @@
+package diff
+
+import (
+	"testing"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
++import "fmt"
+@@ -10,2 +11,3 @@ func main() {
+-	println("hi")
++	fmt.Println("hi")
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 3333333..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-old
+diff --git a/logo.png b/logo.png
+index 4444444..5555555 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git "a/dir/with space.go" "b/dir/with space.go"
+new file mode 100644
+--- /dev/null
++++ "b/dir/with space.go"
+@@ -0,0 +1 @@
++package dir
+`
+
+func TestParse(t *testing.T) {
+	files := Parse("warning: preamble is ignored\n" + sampleDiff)
+
+	expected := []struct {
+		path  string
+		hunks int
+	}{
+		{path: "main.go", hunks: 2},
+		{path: "old.txt", hunks: 1},
+		{path: "logo.png", hunks: 0},
+		{path: "dir/with space.go", hunks: 1},
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %d files, got %d", len(expected), len(files))
+	}
+	for i, e := range expected {
+		if files[i].Path != e.path {
+			t.Errorf("File %d: expected path %q, got %q", i, e.path, files[i].Path)
+		}
+		if len(files[i].Hunks) != e.hunks {
+			t.Errorf("File %d: expected %d hunks, got %d", i, e.hunks, len(files[i].Hunks))
+		}
+	}
+
+	// Reassembling the files reproduces the diff
+	var joined string
+	for _, f := range files {
+		joined += f.String()
+	}
+	if joined != sampleDiff {
+		t.Errorf("Expected round trip, got:\n%s", joined)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	if files := Parse(""); len(files) != 0 {
+		t.Errorf("Expected no files, got %d", len(files))
+	}
+}
@@ -0,0 +1,205 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reasons a file is left out of the prompt
+const (
+	ReasonLockfile  = "lockfile"
+	ReasonVendored  = "vendored"
+	ReasonMinified  = "minified"
+	ReasonGenerated = "generated"
+	ReasonBinary    = "binary"
+	ReasonExcluded  = "excluded"
+)
+
+// builtinExcludes are applied before .gitattributes and configured excludes,
+// so either can re-include a file with a negated pattern
+var builtinExcludes = []struct {
+	reason string
+	globs  []string
+}{
+	{ReasonLockfile, []string{
+		"go.sum", "package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb",
+		"Cargo.lock", "Gemfile.lock", "poetry.lock", "Pipfile.lock", "uv.lock", "composer.lock",
+		"mix.lock", "pubspec.lock", "Podfile.lock", "flake.lock", ".terraform.lock.hcl",
+	}},
+	{ReasonVendored, []string{"vendor/", "node_modules/", "bower_components/"}},
+	{ReasonMinified, []string{"*.min.js", "*.min.css", "*.js.map", "*.css.map"}},
+	{ReasonGenerated, []string{"*.pb.go", "*_pb2.py"}},
+}
+
+// Omission records a file that was replaced by a one-line summary
+type Omission struct {
+	Path   string
+	Reason string
+	// Lines is the number of added and removed lines
+	Lines  int
+	Binary bool
+}
+
+// String returns the summary line, e.g. "go.sum: 120 lines changed (lockfile)"
+func (o Omission) String() string {
+	if o.Binary {
+		return fmt.Sprintf("%s: binary file changed (%s)", o.Path, o.Reason)
+	}
+	return fmt.Sprintf("%s: %d lines changed (%s)", o.Path, o.Lines, o.Reason)
+}
+
+// rule is a pattern and the reason files matching it are omitted
+type rule struct {
+	pattern
+	reason string
+}
+
+// Filter decides which files in a diff are sent to the model
+type Filter struct {
+	rules []rule
+}
+
+// NewFilter builds a filter from the built-in defaults (unless disabled),
+// the linguist-generated and linguist-vendored attributes in the
+// repository's top-level .gitattributes, and the configured excludes, in
+// that order. As in .gitignore, the last matching pattern wins.
+func NewFilter(opts Options, repoRoot string) (*Filter, error) {
+	f := &Filter{}
+	if opts.BuiltinExcludes {
+		for _, group := range builtinExcludes {
+			for _, glob := range group.globs {
+				f.add(glob, group.reason)
+			}
+		}
+	}
+
+	if repoRoot != "" {
+		data, err := os.ReadFile(filepath.Join(repoRoot, ".gitattributes"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read .gitattributes: %w", err)
+		}
+		f.addAttributes(string(data))
+	}
+
+	for _, glob := range opts.Exclude {
+		f.add(glob, ReasonExcluded)
+	}
+	return f, nil
+}
+
+// add appends a gitignore-style pattern
+func (f *Filter) add(glob, reason string) {
+	if p, ok := compilePattern(glob); ok {
+		f.rules = append(f.rules, rule{pattern: p, reason: reason})
+	}
+}
+
+// addAttributes reads linguist attributes from .gitattributes content
+func (f *Filter) addAttributes(data string) {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			set, name := attributeState(attr)
+			var reason string
+			switch name {
+			case "linguist-generated":
+				reason = ReasonGenerated
+			case "linguist-vendored":
+				reason = ReasonVendored
+			default:
+				continue
+			}
+			if p, ok := compilePattern(fields[0]); ok {
+				p.negate = !set
+				f.rules = append(f.rules, rule{pattern: p, reason: reason})
+			}
+		}
+	}
+}
+
+// attributeState parses "attr", "-attr", "attr=true" and "attr=false"
+func attributeState(attr string) (bool, string) {
+	if strings.HasPrefix(attr, "-") || strings.HasPrefix(attr, "!") {
+		return false, attr[1:]
+	}
+	name, value, found := strings.Cut(attr, "=")
+	if found {
+		return value != "false", name
+	}
+	return true, name
+}
+
+// Reason returns why path is omitted, or "" if it is sent to the model
+func (f *Filter) Reason(path string) string {
+	reason := ""
+	for _, r := range f.rules {
+		if r.match(path) {
+			if r.negate {
+				reason = ""
+			} else {
+				reason = r.reason
+			}
+		}
+	}
+	return reason
+}
+
+// Apply replaces the diff of each filtered or binary file with a one-line
+// summary listed after the remaining diff
+func (f *Filter) Apply(text string) (string, []Omission) {
+	files := Parse(text)
+	if len(files) == 0 {
+		return text, nil
+	}
+
+	var kept strings.Builder
+	var omitted []Omission
+	for _, file := range files {
+		reason := f.Reason(file.Path)
+		binary := isBinary(file)
+		if reason == "" && binary {
+			reason = ReasonBinary
+		}
+		if reason == "" {
+			kept.WriteString(file.String())
+			continue
+		}
+		omitted = append(omitted, Omission{Path: file.Path, Reason: reason, Lines: changedLines(file), Binary: binary})
+	}
+
+	if len(omitted) > 0 {
+		kept.WriteString("\nFiles summarised without their diff:\n")
+		for _, o := range omitted {
+			kept.WriteString(o.String() + "\n")
+		}
+	}
+	return kept.String(), omitted
+}
+
+// isBinary reports whether git reported the file as binary
+func isBinary(f File) bool {
+	for _, line := range strings.Split(f.Header, "\n") {
+		if strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch" {
+			return true
+		}
+	}
+	return false
+}
+
+// changedLines counts added and removed lines in the file's hunks
+func changedLines(f File) int {
+	n := 0
+	for _, h := range f.Hunks {
+		for _, line := range strings.Split(h, "\n") {
+			if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+				n++
+			}
+		}
+	}
+	return n
+}
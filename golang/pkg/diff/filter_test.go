@@ -0,0 +1,119 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const filterDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1 +1 @@
+-package old
++package main
+diff --git a/go.sum b/go.sum
+--- a/go.sum
++++ b/go.sum
+@@ -1,2 +1,3 @@
+-a v1 h1:x
++a v2 h1:y
++b v1 h1:z
+ c v1 h1:w
+diff --git a/logo.png b/logo.png
+index 4444444..5555555 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/api/types_gen.go b/api/types_gen.go
+--- a/api/types_gen.go
++++ b/api/types_gen.go
+@@ -1 +1 @@
+-x
++y
+diff --git a/dist/app.min.js b/dist/app.min.js
+--- a/dist/app.min.js
++++ b/dist/app.min.js
+@@ -1 +1 @@
+-a
++b
+`
+
+func TestFilterApply(t *testing.T) {
+	repo := t.TempDir()
+	attrs := "# generated code\napi/*_gen.go linguist-generated\n*.min.js -linguist-generated\n"
+	if err := os.WriteFile(filepath.Join(repo, ".gitattributes"), []byte(attrs), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultOptions()
+	opts.Exclude = []string{"dist/", "!dist/app.min.js"}
+	filter, err := NewFilter(opts, repo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	out, omitted := filter.Apply(filterDiff)
+
+	expected := []string{
+		"go.sum: 3 lines changed (lockfile)",
+		"logo.png: binary file changed (binary)",
+		"api/types_gen.go: 2 lines changed (generated)",
+	}
+	if len(omitted) != len(expected) {
+		t.Fatalf("Expected %d omissions, got %v", len(expected), omitted)
+	}
+	for i, want := range expected {
+		if omitted[i].String() != want {
+			t.Errorf("Expected %q, got %q", want, omitted[i].String())
+		}
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("Expected output to contain %q", want)
+		}
+	}
+
+	// The negated exclude re-includes the minified file over the built-in
+	if !strings.Contains(out, "+package main") || !strings.Contains(out, "diff --git a/dist/app.min.js") {
+		t.Errorf("Expected kept files in output, got:\n%s", out)
+	}
+	if strings.Contains(out, "+b v1 h1:z") {
+		t.Error("Expected lockfile diff to be removed")
+	}
+}
+
+func TestFilterReason(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Exclude = []string{"*.snap", "!go.sum"}
+	filter, err := NewFilter(opts, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := map[string]string{
+		"main.go":                    "",
+		"go.sum":                     "",
+		"web/package-lock.json":      ReasonLockfile,
+		"vendor/golang.org/x/net.go": ReasonVendored,
+		"static/site.min.css":        ReasonMinified,
+		"api/service.pb.go":          ReasonGenerated,
+		"ui/__snapshots__/a.snap":    ReasonExcluded,
+	}
+	for path, want := range tests {
+		if got := filter.Reason(path); got != want {
+			t.Errorf("%s: expected reason %q, got %q", path, want, got)
+		}
+	}
+
+	opts.BuiltinExcludes = false
+	filter, _ = NewFilter(opts, "")
+	if got := filter.Reason("yarn.lock"); got != "" {
+		t.Errorf("Expected no built-in excludes, got %q", got)
+	}
+}
+
+func TestFilterApplyNotADiff(t *testing.T) {
+	filter, _ := NewFilter(DefaultOptions(), "")
+	out, omitted := filter.Apply("plain text")
+	if out != "plain text" || omitted != nil {
+		t.Errorf("Expected text unchanged, got %q", out)
+	}
+}
@@ -0,0 +1,95 @@
+package diff
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pattern is a compiled gitignore-style glob
+type pattern struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// compilePattern converts a gitignore-style glob to a pattern. As in
+// .gitignore, a leading "!" negates, a trailing "/" matches directories
+// only, a pattern without an inner "/" matches at any depth, and "**"
+// matches across directories. It returns false for blank lines and comments.
+func compilePattern(glob string) (pattern, bool) {
+	glob = strings.TrimSpace(glob)
+	if glob == "" || strings.HasPrefix(glob, "#") {
+		return pattern{}, false
+	}
+
+	var p pattern
+	if strings.HasPrefix(glob, "!") {
+		p.negate = true
+		glob = glob[1:]
+	}
+	dirOnly := strings.HasSuffix(glob, "/")
+	glob = strings.TrimSuffix(glob, "/")
+	anchored := strings.Contains(glob, "/")
+	glob = strings.TrimPrefix(glob, "/")
+	if glob == "" {
+		return pattern{}, false
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		// Directory patterns match the files beneath the directory
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return pattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// match reports whether path matches the pattern, ignoring negation
+func (p pattern) match(path string) bool {
+	return p.re.MatchString(path)
+}
@@ -0,0 +1,58 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		glob    string
+		path    string
+		matches bool
+	}{
+		{"go.sum", "go.sum", true},
+		{"go.sum", "golang/go.sum", true},
+		{"go.sum", "go.sums", false},
+		{"*.min.js", "web/dist/app.min.js", true},
+		{"*.min.js", "web/dist/app.js", false},
+		{"vendor/", "vendor/github.com/x/y.go", true},
+		{"vendor/", "golang/vendor/x.go", true},
+		{"vendor/", "vendor", false},
+		{"/build", "build/out.txt", true},
+		{"/build", "src/build/out.txt", false},
+		{"docs/*.md", "docs/a.md", true},
+		{"docs/*.md", "docs/sub/a.md", false},
+		{"docs/**/*.md", "docs/sub/deep/a.md", true},
+		{"docs/**/*.md", "docs/a.md", true},
+		{"**/testdata/**", "pkg/x/testdata/golden.txt", true},
+		{"gen/**", "gen/a/b.go", true},
+		{"file?.txt", "file1.txt", true},
+		{"file[0-9].txt", "file7.txt", true},
+		{"file[!0-9].txt", "file7.txt", false},
+		{"generated", "pkg/generated/types.go", true},
+	}
+
+	for _, tt := range tests {
+		p, ok := compilePattern(tt.glob)
+		if !ok {
+			t.Errorf("%q: failed to compile", tt.glob)
+			continue
+		}
+		if got := p.match(tt.path); got != tt.matches {
+			t.Errorf("%q against %q: expected %v, got %v", tt.glob, tt.path, tt.matches, got)
+		}
+	}
+}
+
+func TestCompilePatternSkips(t *testing.T) {
+	for _, glob := range []string{"", "   ", "# comment", "!", "/"} {
+		if _, ok := compilePattern(glob); ok {
+			t.Errorf("Expected %q to be skipped", glob)
+		}
+	}
+
+	p, ok := compilePattern("!go.sum")
+	if !ok || !p.negate || !p.match("go.sum") {
+		t.Error("Expected negated pattern to compile and match")
+	}
+}
@@ -0,0 +1,55 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/redact"
+)
+
+// Prepare readies a diff for the prompt: lockfiles, generated and binary
+// files are replaced with one-line summaries, secrets are redacted, and the
+// rest is kept within opts.MaxTokens by Fit, summarising it in parallel
+// chunks with generate when it is too large. What was left out, redacted or
+// summarised is reported on status, which may be nil.
+func Prepare(ctx context.Context, opts Options, redactOpts redact.Options, text, repoRoot string, generate GenerateFunc, status io.Writer) (Result, error) {
+	if status == nil {
+		status = io.Discard
+	}
+	filter, err := NewFilter(opts, repoRoot)
+	if err != nil {
+		return Result{}, err
+	}
+	text, omitted := filter.Apply(text)
+	if len(omitted) > 0 {
+		names := make([]string, len(omitted))
+		for i, o := range omitted {
+			names[i] = fmt.Sprintf("%s (%s)", o.Path, o.Reason)
+		}
+		fmt.Fprintf(status, "🧹 :: Summarised without their diff: %s\n", strings.Join(names, ", "))
+	}
+
+	// Nothing leaves the machine before secrets are redacted
+	text, findings, err := redact.Apply(redactOpts, text)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(findings) > 0 {
+		locations := make([]string, len(findings))
+		for i, f := range findings {
+			locations[i] = f.String()
+		}
+		fmt.Fprintf(status, "🔒 :: Redacted %d possible secret(s) before sending: %s\n", len(findings), strings.Join(locations, ", "))
+	}
+
+	return Fit(ctx, text, opts, generate, func(done, total int) {
+		if done == 0 {
+			fmt.Fprintf(status, "⚠ :: Diff is about %d tokens, over the budget of %d; summarising it in %d parts first\n",
+				EstimateTokens(text), opts.withDefaults().MaxTokens, total)
+			return
+		}
+		fmt.Fprintf(status, "🧩 :: Summarised part %d/%d\n", done, total)
+	})
+}
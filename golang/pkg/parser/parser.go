@@ -6,14 +6,13 @@ import (
 	"math/rand"
 	"regexp"
 	"strings"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/changelog"
+	"github.com/gudlyf/GudCommit/golang/pkg/convention"
 )
 
 // CommitMessage represents a structured commit message
-type CommitMessage struct {
-	Type        string `json:"type"`
-	Scope       string `json:"scope"`
-	Description string `json:"description"`
-}
+type CommitMessage = convention.Message
 
 // CommitResponse represents the JSON response from Bedrock
 type CommitResponse struct {
@@ -22,9 +21,12 @@ type CommitResponse struct {
 
 // ChangelogEntry represents a changelog section
 type ChangelogEntry struct {
-	Added   []string `json:"added"`
-	Changed []string `json:"changed"`
-	Removed []string `json:"removed"`
+	Added      []string `json:"added"`
+	Changed    []string `json:"changed"`
+	Deprecated []string `json:"deprecated"`
+	Removed    []string `json:"removed"`
+	Fixed      []string `json:"fixed"`
+	Security   []string `json:"security"`
 }
 
 // ChangelogResponse represents the JSON response from Bedrock
@@ -44,6 +46,12 @@ func GenerateRandomString(length int) string {
 
 // ParseCommitResponse parses the response from Bedrock and returns formatted commit messages
 func ParseCommitResponse(response string) ([]string, error) {
+	return ParseCommitResponseWithRules(response, convention.DefaultRules())
+}
+
+// ParseCommitResponseWithRules is ParseCommitResponse with a configured type
+// vocabulary and scope rules
+func ParseCommitResponseWithRules(response string, rules convention.Rules) ([]string, error) {
 	// Clean the response - remove any markdown formatting or extra text
 	cleanedResponse := regexp.MustCompile("```json\n?").ReplaceAllString(response, "")
 	cleanedResponse = regexp.MustCompile("```\n?").ReplaceAllString(cleanedResponse, "")
@@ -54,7 +62,7 @@ func ParseCommitResponse(response string) ([]string, error) {
 	var commitResp CommitResponse
 	if err := json.Unmarshal([]byte(cleanedResponse), &commitResp); err != nil {
 		// Fallback: try to extract conventional commit format from the response
-		fallbackCommits := ExtractFallbackCommits(response)
+		fallbackCommits := ExtractFallbackCommitsWithRules(response, rules)
 		if len(fallbackCommits) > 0 {
 			return fallbackCommits, nil
 		}
@@ -66,21 +74,17 @@ func ParseCommitResponse(response string) ([]string, error) {
 	}
 
 	var messages []string
-	validTypes := map[string]bool{
-		"feat": true, "fix": true, "build": true, "chore": true, "ci": true,
-		"docs": true, "style": true, "refactor": true, "perf": true, "test": true,
-	}
-
 	for _, commit := range commitResp.Commits {
-		if commit.Type == "" || commit.Scope == "" || commit.Description == "" {
+		commit.Scope = rules.NormalizeScope(commit.Scope)
+		if commit.Type == "" || commit.Description == "" || (rules.UsesScope() && commit.Scope == "") {
 			return nil, nil
 		}
 
-		if !validTypes[commit.Type] {
+		if rules.Check(commit.Type, commit.Scope) != nil {
 			return nil, nil
 		}
 
-		messages = append(messages, fmt.Sprintf("%s(%s): %s", commit.Type, commit.Scope, commit.Description))
+		messages = append(messages, commit.Render(rules.WrapWidth))
 	}
 
 	return messages, nil
@@ -88,12 +92,19 @@ func ParseCommitResponse(response string) ([]string, error) {
 
 // ExtractFallbackCommits extracts conventional commit messages from unstructured response
 func ExtractFallbackCommits(response string) []string {
-	conventionalCommitRegex := regexp.MustCompile(`^(feat|fix|build|chore|ci|docs|style|refactor|perf|test)\([^)]+\): .+$`)
+	return ExtractFallbackCommitsWithRules(response, convention.DefaultRules())
+}
+
+// ExtractFallbackCommitsWithRules is ExtractFallbackCommits with a configured
+// type vocabulary and scope rules
+func ExtractFallbackCommitsWithRules(response string, rules convention.Rules) []string {
+	conventionalCommitRegex := rules.HeaderPattern()
 	lines := strings.Split(response, "\n")
 	var commits []string
 
 	for _, line := range lines {
-		if conventionalCommitRegex.MatchString(strings.TrimSpace(line)) {
+		match := conventionalCommitRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match != nil && rules.AllowsScope(match[2]) {
 			commits = append(commits, strings.TrimSpace(line))
 		}
 	}
@@ -103,55 +114,49 @@ func ExtractFallbackCommits(response string) []string {
 
 // ParseChangelogResponse parses the response from Bedrock and returns formatted changelog
 func ParseChangelogResponse(response string) (string, error) {
-	// Clean the response - remove any markdown formatting or extra text
-	cleanedResponse := regexp.MustCompile("```json\n?").ReplaceAllString(response, "")
-	cleanedResponse = regexp.MustCompile("```\n?").ReplaceAllString(cleanedResponse, "")
-	cleanedResponse = regexp.MustCompile("^[^{]*").ReplaceAllString(cleanedResponse, "")
-	cleanedResponse = regexp.MustCompile("[^}]*$").ReplaceAllString(cleanedResponse, "")
-	cleanedResponse = strings.TrimSpace(cleanedResponse)
+	return ParseChangelogResponseWithOptions(response, changelog.DefaultOptions())
+}
 
-	var changelogResp ChangelogResponse
-	if err := json.Unmarshal([]byte(cleanedResponse), &changelogResp); err != nil {
+// ParseChangelogResponseWithOptions is ParseChangelogResponse with configured
+// custom sections. Sections are written in Keep a Changelog order, followed
+// by the custom ones.
+func ParseChangelogResponseWithOptions(response string, opts changelog.Options) (string, error) {
+	entry, err := opts.Parse(response)
+	if err != nil {
 		// Fallback: try to extract changelog format from the response
-		fallbackChangelog := ExtractFallbackChangelog(response)
+		fallbackChangelog := ExtractFallbackChangelogWithOptions(response, opts)
 		if fallbackChangelog != "" {
 			return fallbackChangelog, nil
 		}
 		return "", err
 	}
 
-	var changelog strings.Builder
-
-	if len(changelogResp.Changelog.Removed) > 0 {
-		changelog.WriteString("### Removed\n\n")
-		for _, item := range changelogResp.Changelog.Removed {
-			changelog.WriteString(fmt.Sprintf("- %s\n", item))
-		}
-		changelog.WriteString("\n")
-	}
-
-	if len(changelogResp.Changelog.Added) > 0 {
-		changelog.WriteString("### Added\n\n")
-		for _, item := range changelogResp.Changelog.Added {
-			changelog.WriteString(fmt.Sprintf("- %s\n", item))
-		}
-		changelog.WriteString("\n")
-	}
+	var result strings.Builder
 
-	if len(changelogResp.Changelog.Changed) > 0 {
-		changelog.WriteString("### Changed\n\n")
-		for _, item := range changelogResp.Changelog.Changed {
-			changelog.WriteString(fmt.Sprintf("- %s\n", item))
+	for _, name := range opts.Ordered(entry) {
+		result.WriteString(fmt.Sprintf("### %s\n\n", name))
+		for _, item := range entry[name] {
+			result.WriteString(fmt.Sprintf("- %s\n", item))
 		}
-		changelog.WriteString("\n")
+		result.WriteString("\n")
 	}
 
-	return strings.TrimSpace(changelog.String()), nil
+	return strings.TrimSpace(result.String()), nil
 }
 
 // ExtractFallbackChangelog extracts changelog from unstructured response
 func ExtractFallbackChangelog(response string) string {
-	changelogRegex := regexp.MustCompile(`(### (?:Added|Changed|Removed)\n\n(?:- .+\n?)+)`)
+	return ExtractFallbackChangelogWithOptions(response, changelog.DefaultOptions())
+}
+
+// ExtractFallbackChangelogWithOptions is ExtractFallbackChangelog with
+// configured custom sections
+func ExtractFallbackChangelogWithOptions(response string, opts changelog.Options) string {
+	var names []string
+	for _, s := range opts.All() {
+		names = append(names, regexp.QuoteMeta(s.Name))
+	}
+	changelogRegex := regexp.MustCompile(`(### (?:` + strings.Join(names, "|") + `)\n\n(?:- .+\n?)+)`)
 	matches := changelogRegex.FindAllString(response, -1)
 	return strings.Join(matches, "\n")
 }
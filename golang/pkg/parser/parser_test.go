@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/gudlyf/GudCommit/golang/pkg/changelog"
+	"github.com/gudlyf/GudCommit/golang/pkg/convention"
 )
 
 func TestParseCommitResponse(t *testing.T) {
@@ -147,11 +150,7 @@ func TestParseChangelogResponse(t *testing.T) {
 					"removed": ["Deprecated feature"]
 				}
 			}`,
-			expected: `### Removed
-
-- Deprecated feature
-
-### Added
+			expected: `### Added
 
 - New feature A
 - New feature B
@@ -159,7 +158,54 @@ func TestParseChangelogResponse(t *testing.T) {
 ### Changed
 
 - Updated API
-- Improved performance`,
+- Improved performance
+
+### Removed
+
+- Deprecated feature`,
+			hasError: false,
+		},
+		{
+			name: "All categories in Keep a Changelog order",
+			response: `{
+				"changelog": {
+					"security": ["Escape HTML in errors"],
+					"fixed": ["Handle empty bodies"],
+					"removed": ["The v1 client"],
+					"deprecated": ["The v1 routes"],
+					"changed": ["Faster startup"],
+					"added": ["Retries"]
+				}
+			}`,
+			expected: `### Added
+
+- Retries
+
+### Changed
+
+- Faster startup
+
+### Deprecated
+
+- The v1 routes
+
+### Removed
+
+- The v1 client
+
+### Fixed
+
+- Handle empty bodies
+
+### Security
+
+- Escape HTML in errors`,
+			hasError: false,
+		},
+		{
+			name:     "Fallback markdown with new categories",
+			response: "Here you go:\n\n### Fixed\n\n- Handle empty bodies\n",
+			expected: "### Fixed\n\n- Handle empty bodies\n",
 			hasError: false,
 		},
 		{
@@ -237,6 +283,96 @@ func TestParseChangelogResponse(t *testing.T) {
 	}
 }
 
+func TestParseChangelogResponseWithOptions(t *testing.T) {
+	opts := changelog.Options{Sections: []changelog.Section{{Name: "Performance"}}}
+	response := `{"changelog": {"performance": ["Batch inserts"], "fixed": ["Handle empty bodies"], "misc": ["Tidy up"], "added": []}}`
+	result, err := ParseChangelogResponseWithOptions(response, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Unknown sections are listed under Changed; custom ones come last
+	expected := "### Changed\n\n- Tidy up\n\n### Fixed\n\n- Handle empty bodies\n\n### Performance\n\n- Batch inserts"
+	if result != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, result)
+	}
+
+	if got := ExtractFallbackChangelogWithOptions("### Performance\n\n- Batch inserts\n", opts); got != "### Performance\n\n- Batch inserts\n" {
+		t.Errorf("Expected the custom section in the fallback, got %q", got)
+	}
+}
+
+func TestParseCommitResponseWithRules(t *testing.T) {
+	rules := convention.Rules{
+		Types:         []string{"feat", "revert", "security"},
+		Scopes:        []string{"api", "cli"},
+		ScopeStrategy: convention.ScopePath,
+	}
+
+	tests := []struct {
+		name     string
+		rules    convention.Rules
+		response string
+		expected []string
+	}{
+		{
+			name:     "Custom type and allowed scope",
+			rules:    rules,
+			response: `{"commits": [{"type": "security", "scope": "api", "description": "Redact tokens"}]}`,
+			expected: []string{"security(api): Redact tokens"},
+		},
+		{
+			name:     "Default type not in vocabulary",
+			rules:    rules,
+			response: `{"commits": [{"type": "fix", "scope": "api", "description": "Fix bug"}]}`,
+			expected: nil,
+		},
+		{
+			name:     "Scope outside allowed list",
+			rules:    rules,
+			response: `{"commits": [{"type": "feat", "scope": "db", "description": "Add index"}]}`,
+			expected: nil,
+		},
+		{
+			name:     "Package strategy",
+			rules:    convention.Rules{Types: []string{"feat"}, ScopeStrategy: convention.ScopePackage},
+			response: `{"commits": [{"type": "feat", "scope": "golang/pkg/bedrock/client.go", "description": "Add client"}]}`,
+			expected: []string{"feat(bedrock): Add client"},
+		},
+		{
+			name:     "No scope strategy",
+			rules:    convention.Rules{Types: []string{"feat"}, ScopeStrategy: convention.ScopeNone},
+			response: `{"commits": [{"type": "feat", "scope": "api", "description": "Add endpoint"}]}`,
+			expected: []string{"feat: Add endpoint"},
+		},
+		{
+			name:  "Body and footers",
+			rules: convention.Rules{Types: []string{"feat"}, ScopeStrategy: convention.ScopeDirectory, WrapWidth: 30},
+			response: `{"commits": [{"type": "feat", "scope": "golang/cmd/gudcommit/main.go", "description": "Add body",
+				"body": "Reviewers want to know why a change was made.", "breaking": true,
+				"footers": [{"token": "Refs", "value": "#9"}]}]}`,
+			expected: []string{"feat(golang)!: Add body\n\nReviewers want to know why a\nchange was made.\n\nRefs: #9"},
+		},
+		{
+			name:     "Fallback with custom types",
+			rules:    rules,
+			response: "revert(cli): Undo flag rename\nfix(cli): Not allowed\nsecurity(db): Scope not allowed",
+			expected: []string{"revert(cli): Undo flag rename"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseCommitResponseWithRules(tt.response, tt.rules)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(result, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestExtractFallbackCommits(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,307 @@
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultEntropyThreshold is the Shannon entropy (bits per character) above
+// which a long token is treated as a secret
+const DefaultEntropyThreshold = 4.0
+
+// minEntropyLength is the shortest token checked for entropy
+const minEntropyLength = 24
+
+// Options controls secret redaction
+type Options struct {
+	// Enabled turns redaction on
+	Enabled bool `json:"enabled"`
+	// Abort stops before contacting the model when a secret is found,
+	// instead of sending the diff with placeholders
+	Abort bool `json:"abort"`
+	// Patterns are extra regular expressions to redact; when a pattern has
+	// a capture group only the first group is replaced
+	Patterns []string `json:"patterns"`
+	// EntropyThreshold flags long random-looking tokens; 0 disables it
+	EntropyThreshold float64 `json:"entropy_threshold"`
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{Enabled: true, EntropyThreshold: DefaultEntropyThreshold}
+}
+
+// detector finds one kind of secret on a line
+type detector struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+// builtinDetectors match well-known credential formats. Where a pattern has
+// a capture group only the group is the secret.
+var builtinDetectors = []detector{
+	{"aws-access-key-id", regexp.MustCompile(`\b((?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16})\b`)},
+	{"aws-secret-access-key", regexp.MustCompile(`(?i)aws.{0,20}secret.{0,20}?[=:]\s*["']?([A-Za-z0-9/+]{40})\b`)},
+	{"jwt", regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,})`)},
+	{"github-token", regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{50,})\b`)},
+	{"slack-token", regexp.MustCompile(`\b(xox[abposr]-[A-Za-z0-9-]{10,})\b`)},
+	{"google-api-key", regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})\b`)},
+	{"stripe-key", regexp.MustCompile(`\b((?:sk|rk)_live_[0-9A-Za-z]{20,})\b`)},
+	// KEY=value, KEY: "value" and "key": "value" for names such as
+	// DB_PASSWORD or SECRET_KEY; a value must end at a quote, space, comma,
+	// semicolon or the end of the line, which leaves out calls like
+	// os.Getenv("PASSWORD"), and must not start with "[" so placeholders
+	// from the detectors above are left alone
+	{"password-assignment", regexp.MustCompile(`(?i)[A-Za-z0-9_-]*(?:password|passwd|secret|token|api[_-]?key)[A-Za-z0-9_-]*["']?\s*[:=]\s*["']?([^\s"'(),;\[][^\s"'(),;]{7,})(?:["'\s,;]|$)`)},
+}
+
+// references match values found by a detector that name a secret rather
+// than hold one: environment variable names and ${...} references
+var references = map[string]*regexp.Regexp{
+	"password-assignment": regexp.MustCompile(`^(?:[A-Z][A-Z0-9_]*|\$.*)$`),
+}
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	// entropyToken leaves out "=" so NAME=value keeps its name
+	entropyToken = regexp.MustCompile(`[A-Za-z0-9+/_-]{24,}`)
+	hunkHeader   = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+)
+
+// Finding is a possible secret found in a diff
+type Finding struct {
+	File string
+	// Line is the line number in the new file, or in the old file for
+	// removed lines; 0 if unknown
+	Line int
+	Kind string
+}
+
+// String returns "file:line (kind)"
+func (f Finding) String() string {
+	loc := f.File
+	if loc == "" {
+		loc = "(unknown file)"
+	}
+	if f.Line > 0 {
+		loc += ":" + strconv.Itoa(f.Line)
+	}
+	return fmt.Sprintf("%s (%s)", loc, f.Kind)
+}
+
+// FoundError is returned by callers that abort when secrets are found
+type FoundError struct {
+	Findings []Finding
+}
+
+// Error lists where the possible secrets are
+func (e *FoundError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "found %d possible secret(s) in the diff; nothing was sent to the model:", len(e.Findings))
+	for _, f := range e.Findings {
+		b.WriteString("\n  " + f.String())
+	}
+	b.WriteString("\nunstage them, or set redact.abort to false to send the diff with placeholders")
+	return b.String()
+}
+
+// Redactor replaces secrets in a diff with placeholders
+type Redactor struct {
+	detectors []detector
+	entropy   float64
+}
+
+// New builds a redactor from opts, compiling the configured patterns
+func New(opts Options) (*Redactor, error) {
+	r := &Redactor{detectors: builtinDetectors, entropy: opts.EntropyThreshold}
+	for _, p := range opts.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact.patterns entry %q: %w", p, err)
+		}
+		r.detectors = append(r.detectors, detector{kind: "custom", re: re})
+	}
+	return r, nil
+}
+
+// Apply redacts text as opts asks: it is returned unchanged when redaction
+// is off, and a *FoundError is returned instead when secrets are found and
+// opts.Abort is set
+func Apply(opts Options, text string) (string, []Finding, error) {
+	if !opts.Enabled {
+		return text, nil, nil
+	}
+	r, err := New(opts)
+	if err != nil {
+		return "", nil, err
+	}
+	text, findings := r.Redact(text)
+	if len(findings) > 0 && opts.Abort {
+		return "", nil, &FoundError{Findings: findings}
+	}
+	return text, findings, nil
+}
+
+// Placeholder returns the text that replaces a secret of kind
+func Placeholder(kind string) string {
+	return "[REDACTED:" + kind + "]"
+}
+
+// Redact replaces every secret in a unified diff with a placeholder and
+// reports where each one was found
+func (r *Redactor) Redact(text string) (string, []Finding) {
+	var out []string
+	var findings []Finding
+	file := ""
+	oldLine, newLine := 0, 0
+	inHeader, inKey := false, false
+
+	for _, line := range strings.Split(text, "\n") {
+		// Track the current file and line numbers for findings
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			file = gitPath(line)
+			inHeader, inKey = true, false
+			out = append(out, line)
+			continue
+		case strings.HasPrefix(line, "@@"):
+			if m := hunkHeader.FindStringSubmatch(line); m != nil {
+				oldLine, _ = strconv.Atoi(m[1])
+				newLine, _ = strconv.Atoi(m[2])
+				inHeader = false
+			}
+			out = append(out, line)
+			continue
+		case inHeader:
+			// index, mode, rename and ---/+++ lines
+			out = append(out, line)
+			continue
+		}
+
+		prefix, content := "", line
+		if line != "" && strings.ContainsRune("+- ", rune(line[0])) {
+			prefix, content = line[:1], line[1:]
+		}
+		num := newLine
+		if prefix == "-" {
+			num = oldLine
+		}
+		switch prefix {
+		case "+":
+			newLine++
+		case "-":
+			oldLine++
+		case " ":
+			oldLine++
+			newLine++
+		}
+
+		// Drop private key bodies, keeping one placeholder line
+		if inKey {
+			if privateKeyEnd.MatchString(content) {
+				inKey = false
+			}
+			continue
+		}
+		if privateKeyBegin.MatchString(content) {
+			findings = append(findings, Finding{File: file, Line: num, Kind: "private-key"})
+			out = append(out, prefix+Placeholder("private-key"))
+			inKey = !privateKeyEnd.MatchString(content)
+			continue
+		}
+
+		content, kinds := r.redactLine(content)
+		for _, kind := range kinds {
+			findings = append(findings, Finding{File: file, Line: num, Kind: kind})
+		}
+		out = append(out, prefix+content)
+	}
+
+	return strings.Join(out, "\n"), findings
+}
+
+// redactLine replaces secrets on one line and returns the kinds found
+func (r *Redactor) redactLine(line string) (string, []string) {
+	var kinds []string
+	for _, d := range r.detectors {
+		matched := false
+		line = d.re.ReplaceAllStringFunc(line, func(m string) string {
+			sub := d.re.FindStringSubmatch(m)
+			if len(sub) > 1 && sub[1] != "" {
+				if ref := references[d.kind]; ref != nil && ref.MatchString(sub[1]) {
+					return m
+				}
+				matched = true
+				return strings.Replace(m, sub[1], Placeholder(d.kind), 1)
+			}
+			matched = true
+			return Placeholder(d.kind)
+		})
+		if matched {
+			kinds = append(kinds, d.kind)
+		}
+	}
+
+	if r.entropy > 0 {
+		matched := false
+		line = entropyToken.ReplaceAllStringFunc(line, func(token string) string {
+			if len(token) >= minEntropyLength && mixedCase(token) && Entropy(token) >= r.entropy {
+				matched = true
+				return Placeholder("high-entropy")
+			}
+			return token
+		})
+		if matched {
+			kinds = append(kinds, "high-entropy")
+		}
+	}
+	return line, kinds
+}
+
+// Entropy returns the Shannon entropy of s in bits per character
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	for _, c := range s {
+		counts[c]++
+	}
+	n := float64(len([]rune(s)))
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// mixedCase reports whether s has upper- and lower-case letters and digits,
+// which separates random keys from identifiers and paths
+func mixedCase(s string) bool {
+	var upper, lower, digit bool
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// gitPath reads the new path from a "diff --git a/x b/x" line
+func gitPath(line string) string {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return strings.Trim(rest[i+3:], `"`)
+	}
+	return rest
+}
@@ -0,0 +1,262 @@
+package split
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// Group is one proposed commit: its message and the staged files it covers
+type Group struct {
+	Message string
+	Files   []string
+}
+
+// Plan assigns every staged file to exactly one group. Files are taken from
+// each proposal's list in order, so a file claimed twice stays with the first
+// claimant. Files no proposal claimed join the group with the closest
+// directory, and groups left without files are dropped.
+func Plan(messages []string, claims [][]string, staged []string) []Group {
+	var planned []Group
+	for _, g := range group(messages, claims, staged) {
+		if len(g.Files) > 0 {
+			planned = append(planned, g)
+		}
+	}
+	return planned
+}
+
+// Assign returns, for each staged file, the index of the proposal out of n
+// that Plan would put it in
+func Assign(n int, claims [][]string, staged []string) []int {
+	owners := map[string]int{}
+	for i, g := range group(make([]string, n), claims, staged) {
+		for _, f := range g.Files {
+			owners[f] = i
+		}
+	}
+	assigned := make([]int, len(staged))
+	for i, f := range staged {
+		assigned[i] = owners[f]
+	}
+	return assigned
+}
+
+// group does the work of Plan, keeping one group per message
+func group(messages []string, claims [][]string, staged []string) []Group {
+	stagedSet := make(map[string]bool, len(staged))
+	for _, f := range staged {
+		stagedSet[f] = true
+	}
+
+	groups := make([]Group, len(messages))
+	assigned := map[string]bool{}
+	for i, message := range messages {
+		groups[i].Message = message
+		if i >= len(claims) {
+			continue
+		}
+		for _, f := range claims[i] {
+			f = resolvePath(f, stagedSet)
+			if stagedSet[f] && !assigned[f] {
+				groups[i].Files = append(groups[i].Files, f)
+				assigned[f] = true
+			}
+		}
+	}
+
+	if len(groups) == 0 {
+		return nil
+	}
+
+	for _, f := range staged {
+		if assigned[f] {
+			continue
+		}
+		best, bestScore := 0, -1
+		for i, g := range groups {
+			for _, other := range g.Files {
+				if score := commonDirs(f, other); score > bestScore {
+					best, bestScore = i, score
+				}
+			}
+		}
+		groups[best].Files = append(groups[best].Files, f)
+	}
+	return groups
+}
+
+// Merge moves the files of group from into group into and appends its
+// message as a further paragraph. Indices are zero-based.
+func Merge(groups []Group, into, from int) ([]Group, error) {
+	if into == from || into < 0 || from < 0 || into >= len(groups) || from >= len(groups) {
+		return groups, fmt.Errorf("cannot merge commit %d into %d", from+1, into+1)
+	}
+	groups[into].Files = append(groups[into].Files, groups[from].Files...)
+	groups[into].Message += "\n\n" + groups[from].Message
+	return append(groups[:from], groups[from+1:]...), nil
+}
+
+// Move reassigns file to group to, dropping any group it leaves empty
+func Move(groups []Group, file string, to int) ([]Group, error) {
+	if to < 0 || to >= len(groups) {
+		return groups, fmt.Errorf("no commit %d", to+1)
+	}
+	for i := range groups {
+		for j, f := range groups[i].Files {
+			if f != file {
+				continue
+			}
+			if i == to {
+				return groups, nil
+			}
+			groups[i].Files = append(groups[i].Files[:j], groups[i].Files[j+1:]...)
+			groups[to].Files = append(groups[to].Files, file)
+			if len(groups[i].Files) == 0 {
+				groups = append(groups[:i], groups[i+1:]...)
+			}
+			return groups, nil
+		}
+	}
+	return groups, fmt.Errorf("%s is not staged", file)
+}
+
+// StagedFiles lists the paths with staged changes in the repository at dir.
+// Renames are reported as a deletion and an addition so each path can be
+// committed on its own.
+func StagedFiles(ctx context.Context, dir string) ([]string, error) {
+	out, err := git(ctx, dir, nil, "diff", "--cached", "--name-only", "--no-renames", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// Commit creates one commit per group from the staged changes, in order.
+// The staged snapshot is saved first and every group is staged from it, so
+// unstaged work-tree changes are never included. Staged files no group
+// covers are left staged. If anything fails the branch and index are
+// restored to how they were before.
+func Commit(ctx context.Context, dir string, groups []Group) (err error) {
+	staged, err := StagedFiles(ctx, dir)
+	if err != nil {
+		return err
+	}
+	snapshot, err := git(ctx, dir, nil, "write-tree")
+	if err != nil {
+		return err
+	}
+	head, headErr := git(ctx, dir, nil, "rev-parse", "--verify", "-q", "HEAD")
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Restore even if ctx was canceled midway
+		restoreCtx := context.Background()
+		if headErr == nil {
+			_, _ = git(restoreCtx, dir, nil, "reset", "-q", "--soft", head)
+		} else {
+			_, _ = git(restoreCtx, dir, nil, "update-ref", "-d", "HEAD")
+		}
+		if _, restoreErr := git(restoreCtx, dir, nil, "read-tree", snapshot); restoreErr != nil {
+			err = fmt.Errorf("%w; restoring the index also failed (staged tree %s): %v", err, snapshot, restoreErr)
+		}
+	}()
+
+	// Start from an index with nothing staged
+	if headErr == nil {
+		_, err = git(ctx, dir, nil, "read-tree", head)
+	} else {
+		_, err = git(ctx, dir, nil, "read-tree", "--empty")
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, g := range groups {
+		args := append([]string{"reset", "-q", snapshot, "--"}, g.Files...)
+		if _, err = git(ctx, dir, nil, args...); err != nil {
+			return fmt.Errorf("failed to stage files for commit %d: %w", i+1, err)
+		}
+		if _, err = git(ctx, dir, strings.NewReader(g.Message), "commit", "-q", "-F", "-"); err != nil {
+			return fmt.Errorf("commit %d of %d failed: %w", i+1, len(groups), err)
+		}
+	}
+
+	covered := map[string]bool{}
+	for _, g := range groups {
+		for _, f := range g.Files {
+			covered[f] = true
+		}
+	}
+	var rest []string
+	for _, f := range staged {
+		if !covered[f] {
+			rest = append(rest, f)
+		}
+	}
+	if len(rest) > 0 {
+		if _, err = git(ctx, dir, nil, append([]string{"reset", "-q", snapshot, "--"}, rest...)...); err != nil {
+			return fmt.Errorf("failed to restage files left out of the commits: %w", err)
+		}
+	}
+	return nil
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(ctx context.Context, dir string, stdin *strings.Reader, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	// File names are paths, never globs
+	cmd.Env = append(os.Environ(), "GIT_LITERAL_PATHSPECS=1")
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		msg := strings.TrimSpace(stderr.String() + stdout.String())
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// resolvePath matches a path the model returned to a staged path, allowing
+// for "./" and the "a/" or "b/" prefixes used in diff headers
+func resolvePath(f string, staged map[string]bool) string {
+	f = strings.TrimPrefix(strings.TrimSpace(f), "./")
+	if staged[f] {
+		return f
+	}
+	for _, prefix := range []string{"a/", "b/"} {
+		if trimmed := strings.TrimPrefix(f, prefix); trimmed != f && staged[trimmed] {
+			return trimmed
+		}
+	}
+	return f
+}
+
+// commonDirs counts the leading directories two paths share
+func commonDirs(a, b string) int {
+	da := strings.Split(path.Dir(a), "/")
+	db := strings.Split(path.Dir(b), "/")
+	n := 0
+	for n < len(da) && n < len(db) && da[n] == db[n] && da[n] != "." {
+		n++
+	}
+	return n
+}
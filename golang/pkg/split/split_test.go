@@ -0,0 +1,210 @@
+package split
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	staged := []string{"pkg/api/server.go", "pkg/api/routes.go", "README.md", "pkg/db/store.go"}
+
+	groups := Plan(
+		[]string{"feat(api): Add routes", "docs: Update readme", "chore: Nothing staged"},
+		[][]string{{"b/pkg/api/server.go", "./README.md"}, {"README.md"}, {"missing.go"}},
+		staged,
+	)
+
+	expected := []Group{
+		{Message: "feat(api): Add routes", Files: []string{"pkg/api/server.go", "README.md", "pkg/api/routes.go", "pkg/db/store.go"}},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, groups)
+	}
+
+	// Unclaimed files go to the group sharing the most directories
+	groups = Plan(
+		[]string{"feat(api): Add routes", "fix(db): Fix store"},
+		[][]string{{"pkg/api/server.go"}, {"pkg/db/store.go"}},
+		[]string{"pkg/api/server.go", "pkg/db/store.go", "pkg/db/migrate.go"},
+	)
+	if got := groups[1].Files; !reflect.DeepEqual(got, []string{"pkg/db/store.go", "pkg/db/migrate.go"}) {
+		t.Errorf("Expected migrate.go with the db commit, got %v", got)
+	}
+
+	// Assign keeps proposal indices even for proposals left without files
+	owners := Assign(3,
+		[][]string{{"missing.go"}, {"pkg/api/server.go"}, {"pkg/db/store.go"}},
+		[]string{"pkg/api/server.go", "pkg/db/store.go", "pkg/db/migrate.go"},
+	)
+	if !reflect.DeepEqual(owners, []int{1, 2, 2}) {
+		t.Errorf("Expected [1 2 2], got %v", owners)
+	}
+}
+
+func TestMergeAndMove(t *testing.T) {
+	groups := []Group{
+		{Message: "a", Files: []string{"a.go"}},
+		{Message: "b", Files: []string{"b.go"}},
+		{Message: "c", Files: []string{"c.go", "d.go"}},
+	}
+
+	groups, err := Merge(groups, 0, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Message != "a\n\nb" || !reflect.DeepEqual(groups[0].Files, []string{"a.go", "b.go"}) {
+		t.Errorf("Unexpected merge result: %+v", groups)
+	}
+	if _, err := Merge(groups, 0, 0); err == nil {
+		t.Error("Expected error merging a commit into itself")
+	}
+
+	groups, err = Move(groups, "d.go", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	groups, err = Move(groups, "c.go", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Files) != 4 {
+		t.Errorf("Expected emptied commit to be dropped, got %+v", groups)
+	}
+	if _, err := Move(groups, "missing.go", 0); err == nil {
+		t.Error("Expected error for unknown file")
+	}
+}
+
+// newRepo creates a repository with one commit and returns its path
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig-none"))
+	run(t, dir, "init", "-q")
+	run(t, dir, "config", "user.name", "Test")
+	run(t, dir, "config", "user.email", "test@example.com")
+	write(t, dir, "a.txt", "a\n")
+	write(t, dir, "b.txt", "b\n")
+	run(t, dir, "add", ".")
+	run(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := git(context.Background(), dir, nil, args...)
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return out
+}
+
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	dir := newRepo(t)
+
+	write(t, dir, "a.txt", "a changed\n")
+	write(t, dir, "dir/c [1].txt", "c\n")
+	run(t, dir, "rm", "-q", "b.txt")
+	run(t, dir, "add", "-A")
+	// An unstaged edit must not end up in any commit
+	write(t, dir, "a.txt", "a changed again\n")
+
+	staged, err := StagedFiles(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(staged, []string{"a.txt", "b.txt", "dir/c [1].txt"}) {
+		t.Fatalf("Unexpected staged files: %v", staged)
+	}
+
+	groups := []Group{
+		{Message: "fix: Change a\n\nWith a body.", Files: []string{"a.txt"}},
+		{Message: "feat: Add c, remove b", Files: []string{"dir/c [1].txt", "b.txt"}},
+	}
+	if err := Commit(context.Background(), dir, groups); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	log := run(t, dir, "log", "--format=%B%x00", "-3")
+	if !strings.Contains(log, "feat: Add c, remove b") || !strings.Contains(log, "fix: Change a\n\nWith a body.") {
+		t.Errorf("Unexpected log:\n%s", log)
+	}
+	if files := run(t, dir, "show", "--name-only", "--format=", "HEAD~1"); files != "a.txt" {
+		t.Errorf("Expected first commit to contain a.txt only, got %q", files)
+	}
+	if content := run(t, dir, "show", "HEAD:a.txt"); content != "a changed" {
+		t.Errorf("Expected staged content to be committed, got %q", content)
+	}
+	if status := run(t, dir, "status", "--porcelain"); status != "M a.txt" {
+		t.Errorf("Expected only the unstaged edit to remain, got %q", status)
+	}
+}
+
+func TestCommitLeavesUncoveredFilesStaged(t *testing.T) {
+	dir := newRepo(t)
+
+	write(t, dir, "a.txt", "a changed\n")
+	write(t, dir, "b.txt", "b changed\n")
+	run(t, dir, "add", "-A")
+
+	if err := Commit(context.Background(), dir, []Group{{Message: "fix: Change a", Files: []string{"a.txt"}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if files := run(t, dir, "show", "--name-only", "--format=", "HEAD"); files != "a.txt" {
+		t.Errorf("Expected the commit to contain a.txt only, got %q", files)
+	}
+	if status := run(t, dir, "status", "--porcelain"); status != "M  b.txt" {
+		t.Errorf("Expected b.txt to stay staged, got %q", status)
+	}
+}
+
+func TestCommitRestoresOnFailure(t *testing.T) {
+	dir := newRepo(t)
+	head := run(t, dir, "rev-parse", "HEAD")
+
+	write(t, dir, "a.txt", "a changed\n")
+	write(t, dir, "b.txt", "b changed\n")
+	run(t, dir, "add", "-A")
+	before := run(t, dir, "write-tree")
+
+	// The second commit is rejected by a hook after the first succeeded
+	hook := filepath.Join(dir, ".git", "hooks", "commit-msg")
+	write(t, dir, ".git/hooks/commit-msg", "#!/bin/sh\ngrep -q reject \"$1\" && exit 1\nexit 0\n")
+	if err := os.Chmod(hook, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	groups := []Group{
+		{Message: "fix: Change a", Files: []string{"a.txt"}},
+		{Message: "fix: reject this", Files: []string{"b.txt"}},
+	}
+	if err := Commit(context.Background(), dir, groups); err == nil {
+		t.Fatal("Expected error from rejected commit")
+	}
+
+	if got := run(t, dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("Expected HEAD to be restored to %s, got %s", head, got)
+	}
+	if got := run(t, dir, "write-tree"); got != before {
+		t.Errorf("Expected index to be restored to %s, got %s", before, got)
+	}
+}
@@ -0,0 +1,107 @@
+package term
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// Key is a key press: a printable rune, or one of the named keys below,
+// which are negative so they never collide with a rune
+type Key rune
+
+// Named keys
+const (
+	KeyEnter Key = -(iota + 1)
+	KeyBackspace
+	KeyEscape
+	KeyTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	// KeyUnknown is a control character or escape sequence with no name
+	KeyUnknown
+)
+
+// ReadKey reads one key press from stdin, which should be in character mode
+// (see CharMode). It returns ctx.Err() as soon as ctx is done.
+func ReadKey(ctx context.Context) (Key, error) {
+	in := stdin
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	for {
+		in.drain()
+		if key, n := decodeKey(in.buf); n > 0 {
+			in.buf = in.buf[n:]
+			return key, nil
+		}
+		if err := in.fill(ctx); err != nil {
+			if ctx.Err() == nil {
+				// The input ended inside a key
+				in.buf = nil
+			}
+			return 0, err
+		}
+	}
+}
+
+// decodeKey decodes the key press at the start of b and returns its length,
+// or 0 when b does not hold all of it yet. An escape byte followed by more
+// input is taken as the start of an escape sequence, since terminals send a
+// sequence in one write; a lone escape is the Esc key.
+func decodeKey(b []byte) (Key, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	switch b[0] {
+	case '\r', '\n':
+		return KeyEnter, 1
+	case '\t':
+		return KeyTab, 1
+	case 0x7f, 0x08:
+		return KeyBackspace, 1
+	case 0x1b:
+		if len(b) == 1 {
+			return KeyEscape, 1
+		}
+		return decodeEscape(b)
+	}
+	if b[0] < 0x20 {
+		return KeyUnknown, 1
+	}
+	if b[0] < utf8.RuneSelf {
+		return Key(b[0]), 1
+	}
+	if !utf8.FullRune(b) {
+		return 0, 0
+	}
+	c, n := utf8.DecodeRune(b)
+	return Key(c), n
+}
+
+// decodeEscape decodes a CSI or SS3 escape sequence at the start of b
+func decodeEscape(b []byte) (Key, int) {
+	if b[1] != '[' && b[1] != 'O' {
+		// Alt+key; drop the modifier
+		return Key(b[1]), 2
+	}
+	// Parameters and intermediates run until a final byte in 0x40-0x7e
+	for i := 2; i < len(b); i++ {
+		if b[i] < 0x40 || b[i] > 0x7e {
+			continue
+		}
+		switch b[i] {
+		case 'A':
+			return KeyUp, i + 1
+		case 'B':
+			return KeyDown, i + 1
+		case 'C':
+			return KeyRight, i + 1
+		case 'D':
+			return KeyLeft, i + 1
+		}
+		return KeyUnknown, i + 1
+	}
+	return 0, 0
+}
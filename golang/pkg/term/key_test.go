@@ -0,0 +1,43 @@
+package term
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	stdin = newInput(strings.NewReader("a\x1b[A\x1bOB\x1b[1;5C\x1b[D\t\r\x7fé\x1b[3~\x01\x1bx\x1b"))
+
+	var keys []Key
+	for {
+		key, err := ReadKey(context.Background())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	expected := []Key{'a', KeyUp, KeyDown, KeyRight, KeyLeft, KeyTab, KeyEnter, KeyBackspace, 'é', KeyUnknown, KeyUnknown, 'x', KeyEscape}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+}
+
+func TestReadKeyCanceled(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	stdin = newInput(pr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ReadKey(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+//go:build !windows
+
+package term
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// CharMode switches the terminal on f to character mode: key presses are
+// delivered immediately and not echoed, while Ctrl-C still interrupts and
+// output is processed as usual. It returns a function that restores the
+// previous mode.
+func CharMode(f *os.File) (func() error, error) {
+	saved, err := stty(f, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal mode: %w", err)
+	}
+	if _, err := stty(f, "-icanon", "-echo", "min", "1", "time", "0"); err != nil {
+		return nil, fmt.Errorf("failed to set terminal mode: %w", err)
+	}
+	return func() error {
+		_, err := stty(f, strings.TrimSpace(saved))
+		return err
+	}, nil
+}
+
+// Size returns the width and height of the terminal on f, falling back to
+// $COLUMNS and $LINES and then 80x24
+func Size(f *os.File) (int, int) {
+	if out, err := stty(f, "size"); err == nil {
+		var rows, cols int
+		if _, err := fmt.Sscan(out, &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return cols, rows
+		}
+	}
+	return envSize()
+}
+
+// stty runs stty with f as its terminal
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// envSize reads the terminal size from the environment
+func envSize() (int, int) {
+	width, height := 80, 24
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		width = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		height = n
+	}
+	return width, height
+}
@@ -0,0 +1,16 @@
+package term
+
+import (
+	"errors"
+	"os"
+)
+
+// CharMode is not supported on Windows; callers fall back to line prompts
+func CharMode(f *os.File) (func() error, error) {
+	return nil, errors.New("character mode is not supported on Windows")
+}
+
+// Size returns the default terminal size on Windows
+func Size(f *os.File) (int, int) {
+	return 80, 24
+}
@@ -0,0 +1,118 @@
+package term
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ExitInterrupted is the exit code used when the user cancels with Ctrl-C
+const ExitInterrupted = 130
+
+// stdin is shared so input is not lost between prompts
+var stdin = newInput(os.Stdin)
+
+// input reads from its source in one goroutine, started on first use, and
+// hands the bytes to ReadLine and ReadKey. A read that gives up when its
+// context is done leaves nothing blocked on the source, so the next read
+// gets the input that arrives instead of losing it.
+type input struct {
+	// mu serialises readers
+	mu     sync.Mutex
+	src    io.Reader
+	start  sync.Once
+	chunks chan []byte
+	// err is the source's error, set before chunks is closed
+	err error
+	// buf holds bytes received but not read yet
+	buf []byte
+}
+
+func newInput(src io.Reader) *input {
+	return &input{src: src, chunks: make(chan []byte)}
+}
+
+// run copies the source to chunks until it fails
+func (in *input) run() {
+	for {
+		b := make([]byte, 4096)
+		n, err := in.src.Read(b)
+		if n > 0 {
+			in.chunks <- b[:n]
+		}
+		if err != nil {
+			in.err = err
+			close(in.chunks)
+			return
+		}
+	}
+}
+
+// fill waits for more input and adds it to buf. It returns the source's
+// error once the source is done, or ctx.Err() when ctx is done first.
+func (in *input) fill(ctx context.Context) error {
+	in.start.Do(func() { go in.run() })
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case b, ok := <-in.chunks:
+		if !ok {
+			return in.err
+		}
+		in.buf = append(in.buf, b...)
+		return nil
+	}
+}
+
+// drain adds input that has already arrived to buf without waiting
+func (in *input) drain() {
+	for {
+		select {
+		case b, ok := <-in.chunks:
+			if !ok {
+				return
+			}
+			in.buf = append(in.buf, b...)
+		default:
+			return
+		}
+	}
+}
+
+// ReadLine reads one line from stdin with surrounding whitespace removed.
+// It returns ctx.Err() as soon as ctx is done, even if no input arrives.
+func ReadLine(ctx context.Context) (string, error) {
+	in := stdin
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	for {
+		if i := bytes.IndexByte(in.buf, '\n'); i >= 0 {
+			line := string(in.buf[:i])
+			in.buf = in.buf[i+1:]
+			return strings.TrimSpace(line), nil
+		}
+		if err := in.fill(ctx); err != nil {
+			// A final line without a newline is still a line
+			if ctx.Err() != nil || len(in.buf) == 0 {
+				return "", err
+			}
+			line := string(in.buf)
+			in.buf = nil
+			return strings.TrimSpace(line), nil
+		}
+	}
+}
+
+// IsTerminal reports whether f is connected to a character device such as a
+// terminal, as opposed to a pipe or file.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
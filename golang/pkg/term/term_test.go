@@ -0,0 +1,66 @@
+package term
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLine(t *testing.T) {
+	stdin = newInput(strings.NewReader("  yes  \nno"))
+
+	line, err := ReadLine(context.Background())
+	if err != nil || line != "yes" {
+		t.Errorf("Expected 'yes', got %q (%v)", line, err)
+	}
+
+	// A final line without a newline is still returned
+	line, err = ReadLine(context.Background())
+	if err != nil || line != "no" {
+		t.Errorf("Expected 'no', got %q (%v)", line, err)
+	}
+
+	if _, err := ReadLine(context.Background()); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+}
+
+func TestReadLineCanceled(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	stdin = newInput(pr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := ReadLine(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestReadAfterCanceled(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	stdin = newInput(pr)
+
+	// Reads that give up leave the input to the next read
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ReadKey(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected canceled, got %v", err)
+	}
+	if _, err := ReadLine(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected canceled, got %v", err)
+	}
+
+	go pw.Write([]byte("yes\nq"))
+	if line, err := ReadLine(context.Background()); err != nil || line != "yes" {
+		t.Errorf("Expected 'yes', got %q (%v)", line, err)
+	}
+	if key, err := ReadKey(context.Background()); err != nil || key != 'q' {
+		t.Errorf("Expected 'q', got %v (%v)", key, err)
+	}
+}